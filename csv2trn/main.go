@@ -26,258 +26,261 @@ in an account statement to a selected format.
 A transaction is the transfer of an amount of currency between accounts on a particular day.
 It is described by a memo and code, also called the description and transaction type.
 A statement and its records belong to an account, which is called this account in transactions from those records.
-
-CSV2trn reads a statement from standard input.
+This account is given by a statement's suffix, flag -t, a field in the records or, failing those,
+the input format's this account name.
+A statement file name may be followed by a colon and its this account e.g. "jan.csv:Assets:Current",
+so that one run imports statements from several accounts.
+Alternatively, if flag -t is not set, flag -t-from-name derives this account from each statement's file name
+by a regular expression whose first parenthesised subexpression, or else whole match, is the account
+with its dashes replaced by colons e.g. "^(.*)-[0-9]{4}-[0-9]{2}\.csv$" for "Assets-Current-2025-05.csv".
+A file name that does not match leaves this account to the other sources.
+
+CSV2trn reads statements from the named files or, if there are none, one statement from standard input.
+A file with extension ".gz" is decompressed with gzip.
+A file with extension ".zip" is an archive of statements e.g. a year of monthly statements,
+whose members are read in name order, with messages about a member prefixed by the archive and member names.
+It skips any leading UTF-8 byte order mark (BOM) in a statement.
 It parses each line as a transaction CSV record following an input format
 and warns about lines that cannot be parsed to standard error.
-The input format defaults to this module's CSV record (mcsv), but it is usually loaded from an XML file.
+With flag -strict, such a line instead causes csv2trn to exit with a non-zero status without writing transactions.
+If no transactions are parsed from a statement that is not empty, which usually means its input format is wrong,
+csv2trn exits with a non-zero status, unless flag -allow-empty is set.
+The input format defaults to this module's CSV record (mcsv), but it is usually loaded from an XML file,
+or a JSON file with extension ".json" whose object has the same members as the XML elements.
+An input format with columns is for statements of fixed-width lines, rather than CSV records,
+whose fields are at those character positions.
+An input format with column names maps its fields to the names of columns in the statement's header record,
+rather than giving their indexes, so that it still works when a bank adds or reorders columns.
+Formats built into csv2trn, such as mcsv, can be selected by name instead.
+If flag -f is repeated, the format of each statement is detected:
+it is the first format whose header matches the statement's first record or, failing that,
+the first format that parses the most records e.g. because its number of fields is right.
+For a quick one-off or to explore an unknown statement, flag -fields gives a format inline
+as key=value pairs e.g. "date=2,memo=3,amount=15,dateLayout=02-01-2006,nfields=16",
+whose keys are the XML element names below, case insensitive and without the final I of field indexes.
+Flag -dump-format writes the input format in XML, which is a starting point for a new format.
 In XML, the mcsv format is:
 
 	<CSVRecordFormat>
 	    <NFields>7</NFields><!-- The number of fields in the record. -->
+	        <Header></Header><!-- Optional first record e.g. "Date,Memo,Amount", which is skipped. -->
+	        <LazyQuotes>false</LazyQuotes><!-- Whether quotes inside unquoted fields are allowed. -->
+	        <CommentChar></CommentChar><!-- Optional e.g. "#" for lines of metadata, which are skipped. -->
+	        <Columns><!-- Optional character positions of each field, if records are fixed width. -->
+	            <Column><Start>1</Start><End>10</End></Column>
+	        </Columns>
+	        <ExactNFields>false</ExactNFields><!-- Whether extra trailing empty fields are rejected. -->
+	        <IgnoreLeadingColumns>0</IgnoreLeadingColumns><!-- Optional e.g. 1 for a row number dropped from records. -->
+	        <ColumnNames><!-- Optional header names of columns, whose indexes are found from each statement. -->
+	            <ColumnName><Field>DateI</Field><Name>Transaction Date</Name></ColumnName>
+	        </ColumnNames>
 
 	    <!-- The index of each field: 1..NFields or zero if it is not provided. -->
 	    <DateI>1</DateI>
 	        <DateLayout>2006-01-02</DateLayout><!-- The default Go date layout time.DateOnly. -->
+	        <DateLayouts><!-- Optional further layouts tried in order if a date does not match. -->
+	            <DateLayout>02/01/2006</DateLayout>
+	        </DateLayouts>
+	        <MonthNames><!-- Optional local month names substituted before dates are parsed. -->
+	            <MonthName><Local>janvier</Local><English>January</English></MonthName>
+	        </MonthNames>
 	    <ThisAccountI>2</ThisAccountI>
+	        <ThisAccountName></ThisAccountName><!-- Optional this account if the records do not contain it. -->
+	        <NormaliseAccountNumbers>false</NormaliseAccountNumbers><!-- Whether account fields are numbers e.g. IBANs to normalise. -->
 	    <OtherAccountI>3</OtherAccountI>
 	    <CodeI>4</CodeI>
+	        <CreditCode></CreditCode><!-- Optional codes e.g. "CR" and "DR" if the code index is zero. -->
+	        <DebitCode></DebitCode>
 	    <MemoI>5</MemoI>
+	        <Memo2I>0</Memo2I><!-- Optional continuations of the memo e.g. "Narrative2", joined by the separator. -->
+	        <Memo3I>0</Memo3I>
+	            <MemoSeparator></MemoSeparator><!-- Optional e.g. " / "; a single space by default. -->
+	        <MemoReplacements><!-- Optional regular expressions replaced in order in memos. -->
+	            <MemoReplacement><Pattern>^POS PURCHASE [0-9]+ </Pattern><Replacement></Replacement></MemoReplacement>
+	        </MemoReplacements>
+	        <CleanMemo>false</CleanMemo><!-- Whether repeated spaces in memos are collapsed. -->
+	    <NoteI>0</NoteI><!-- An optional annotation written as a Ledger comment. -->
+	    <PayeeI>0</PayeeI><!-- An optional payee written as the Ledger payee, with the memo as metadata. -->
+	    <ReferenceI>0</ReferenceI><!-- An optional bank reference kept as Ledger tag "ref" e.g. "; ref: 18832946". -->
 	    <AmountI>6</AmountI>
+	        <SignI>0</SignI><!-- An optional "CR" or "DR" sign of an unsigned amount. -->
 	        <CreditI>0</CreditI>
 	        <DebitI>0</DebitI>
+	            <SignedDebit>false</SignedDebit><!-- Whether debits may have a minus sign. -->
+	            <SignedCreditDebit>false</SignedCreditDebit><!-- Whether a minus sign reverses a credit or debit. -->
+	        <BalanceI>0</BalanceI><!-- An optional balance of this account after the transaction. -->
+	        <AllowZeroAmount>false</AllowZeroAmount><!-- Whether zero amounts e.g. "0.00" are accepted. -->
+	        <MaxDecimalPlaces>0</MaxDecimalPlaces><!-- Optional e.g. 2; an amount with more is reported. -->
+	        <MinorUnitDigits>0</MinorUnitDigits><!-- Optional e.g. 2 if amounts are in cents e.g. "16200". -->
 	    <CurrencyI>7</CurrencyI>
+	        <StrictCurrency>false</StrictCurrency><!-- Whether currencies must be a symbol or code e.g. "GBP". -->
+	        <CurrencyInAmount>false</CurrencyInAmount><!-- Whether amounts may contain currencies e.g. "NZD $162.00". -->
+	        <PriceI>0</PriceI><!-- An optional price of one unit of the currency e.g. "0.85 GBP". -->
+
+	    <!-- The separators in amount, credit and debit fields. -->
+	    <DecimalSeparator>.</DecimalSeparator><!-- The default. -->
+	    <ThousandsSeparator></ThousandsSeparator><!-- Optional e.g. "," for "1,234.56". -->
 	</CSVRecordFormat>
 
 If the other account field is not provided then its default value is "Imbalance".
 
-CSV2trn orders transactions by date ascending and writes them to standard output in the selected format:
-[Ledger] journal entries (lent) or mcsv.
+Rules, which are loaded from an XML file, adjust transactions after they are parsed.
+An invert rule negates the amounts in transactions belonging to its this account,
+for statements whose sign convention is the opposite of Ledger's e.g. for a credit card.
+A currency rule sets the currency of transactions belonging to its this account that have none,
+so that statements from accounts in different currencies can be translated together.
+Currency rules take precedence over flag -c, whose currency is set only in transactions still without one.
+An opening rule replaces the default other account in transactions with its code e.g. "OPEN" for opening balances
+by its account, which defaults to "Equity:Opening Balances".
+An other account rule replaces the default other account "Imbalance" in transactions belonging to its this account.
+A fee rule splits a fee, either a fraction or a fixed amount, from a transaction whose memo matches a regular expression.
+In Ledger journal entries, the fee is posted to the rule's account.
+A tag rule tags transactions whose memo matches a regular expression, or all of them if it is empty,
+which is written as Ledger metadata e.g. "; imported: 2026-10-15" or a tag e.g. "; :reconciled:". For example:
+
+	<Rules>
+	    <Invert>
+	        <ThisAccount>Liabilities:CreditCard</ThisAccount>
+	    </Invert>
+	    <Currency>
+	        <ThisAccount>Assets:Euro</ThisAccount>
+	        <Currency>EUR</Currency>
+	    </Currency>
+	    <Opening>
+	        <Code>OPEN</Code>
+	        <Account>Equity:Opening Balances</Account><!-- Optional. -->
+	    </Opening>
+	    <OtherAccount>
+	        <ThisAccount>Assets:Current</ThisAccount>
+	        <Account>Expenses:Unknown:Current</Account>
+	    </OtherAccount>
+	    <Fee>
+	        <Memo> EUR$</Memo>
+	        <Account>Expenses:Fees</Account>
+	        <Fraction>0.015</Fraction><!-- Or a fixed <Amount>. -->
+	    </Fee>
+	    <Tag>
+	        <Key>imported</Key>
+	        <Value>2026-10-15</Value><!-- Optional; a tag may have a <Memo> too. -->
+	    </Tag>
+	</Rules>
+
+CSV2trn orders each statement's transactions by date ascending, by reversing them if it is in descending order,
+and writes them to standard output in the selected format:
+[Ledger] journal entries (lent), [hledger] journal entries, mcsv,
+an [Open Financial Exchange] (OFX) document, [GnuCash] transaction import CSV or a JSON array.
+Format prices writes a Ledger price directive e.g. "P 1982-10-03 EUR 0.85 GBP"
+for each transaction with a price field and currency, which starts a Ledger price database.
+Format mcsv-summary follows the mcsv records with a subtotal record for each month and currency
+e.g. "1982-10,subtotal,75.36,GBP", then a total record for each currency e.g. "total,75.36,GBP".
+With -o none, it writes only the number of transactions.
+By default, the statement's order is detected from the first and last dates,
+but it can be set by flag -order instead.
+If the statement is in neither order, for example because it concatenates several statements,
+use flag -sort to sort the transactions by date.
+With flag -assert-balance, the Ledger journal entry for the last transaction of each account
+asserts the account's balance from the input format's balance field e.g. "Assets:Current  -5 GBP = 42.42 GBP",
+so that Ledger verifies the statement was imported completely.
+Filtering transactions by their codes makes the assertion fail.
+With flag -declare-accounts, Ledger journal entries are preceded by an account directive
+for each of their accounts e.g. "account Assets:Current", as required by Ledger's strict checking.
+With flag -split, the transactions of each account, such as the sub-accounts in one export file,
+are written to their own file named by substituting the account for the verb in the flag's file name template,
+rather than to standard output.
+Existing files are overwritten.
+Dates are written in layout YYYY-MM-DD, unless flag -odate gives another layout for a downstream program,
+but mcsv records are then no longer read by this module's programs.
+With flag -explicit-plus, positive amounts are written with a plus sign e.g. "+162",
+so that every amount has its sign in the same place when comparing exports.
+With flag -decimal-places, amounts are written in a fixed number of decimal places e.g. "162.50",
+rounded half to even by default or, with flag -rounding, half away from zero as some accountants require.
+With flag -amount-column, the amounts in Ledger journal entries end at that column e.g. 48,
+so that they are right-aligned as by "ledger print" rather than following their accounts by two spaces.
+With flag -against, only transactions not already in the named Ledger journal are written,
+so that a statement overlapping an earlier one can be imported into a growing journal.
+A transaction is already in the journal if an entry there has the same date, this account, amount and currency,
+and each entry, including one marked as a mirror, accounts for at most one transaction.
+The journal's dates are in the output date layout, and entries that cannot be parsed are warned about then ignored.
 
 Usage:
 
-	csv2trn [flags]
+	csv2trn [flags] [statement[:account] ...]
 
 The flags are:
 
+	-against string
+	  	name of Ledger journal file; write only transactions not already in it e.g. to append to it
+	-allow-empty
+	  	allow a statement that is not empty to have no transactions e.g. only a header, rather than exit with an error
+	-amount-column int
+	  	column at which amounts in Ledger journal entries end e.g. 48 to align them, or 0 for none
+	-assert-balance
+	  	assert the balance field of the last transaction of each account in its Ledger journal entry
 	-c string
-	  	Ledger currency e.g. "$" or "GBP"; overrides currency field from input
-	-f string
-	 	name of file containing input CSV record format in XML
+	  	Ledger currency e.g. "$" or "GBP"; used when the currency field from input is empty
+	-clean-memo
+	  	trim memos and collapse repeated white space in them, as does the input format's CleanMemo
+	-cleared-until string
+	  	mark transactions on or before this date YYYY-MM-DD as cleared and the rest as pending in Ledger
+	-crlf
+	  	end output lines with carriage return and line feed e.g. for Windows
+	-decimal-places int
+	  	number of decimal places in which amounts are written e.g. 2, or -1 for as many as each needs (default -1)
+	-declare-accounts
+	  	precede Ledger journal entries with an account directive for each of their accounts
+	-dump-format
+	  	write the input CSV record format in XML then exit
+	-exclude-codes string
+	  	comma-separated list of transaction codes to exclude e.g. "INT,FEE"
+	-explicit-plus
+	  	write positive amounts with a plus sign e.g. "+162" to keep the sign of every amount when comparing exports
+	-f value
+	  	name of built-in input CSV record format e.g. "mcsv", or of file containing it in XML or JSON; may be repeated to detect each statement's format
+	-fields string
+	  	input CSV record format given inline as key=value pairs e.g. "date=2,memo=3,amount=15,dateLayout=02-01-2006,nfields=16"; detected like another -f
 	-h	write this help text then exit
+	-include-codes string
+	  	comma-separated list of transaction codes to include; all are included if the list is empty
 	-o string
-	  	output format name: Ledger journal entry "lent" or "mcsv" (default "mcsv")
+	  	output format name: Ledger journal entry "lent", hledger journal entry "hledger", "mcsv", "mcsv-summary" with monthly subtotals, OFX document "ofx", GnuCash import CSV "gnucash", Ledger price directive "prices", JSON array "json" or "none" to write only the number of transactions (default "mcsv")
+	-odate string
+	  	Go date layout of output dates e.g. "01/02/2006" for Ledger entries, mcsv and GnuCash records (default "2006-01-02")
+	-order string
+	  	order of transactions in the statement: "auto" detected from first and last dates, "asc", "desc" or "keep" as read (default "auto")
+	-r string
+	  	name of file containing rules in XML e.g. for splitting fees
+	-rounding string
+	  	rounding mode of amounts with more decimal places than flag -decimal-places: "half-even" or "half-up" away from zero (default "half-even")
+	-sort
+	  	sort transactions by date, keeping the order of those with the same date
+	-source string
+	  	name of the statement e.g. its file name; tags each Ledger journal entry as its source
+	-split string
+	  	file name template e.g. "%v.journal" for writing each account's transactions to its own file, rather than standard output; "%v" is replaced by this account
+	-strict
+	  	exit with a non-zero status, after writing warnings, if any line cannot be parsed
 	-t string
 	  	the Ledger name of this account e.g. "Assets:Current"; overrides this account field from input
+	-t-from-name value
+	  	regular expression deriving this account from each statement's file name, if flag -t is not set, with dashes replaced by colons e.g. "^(.*)-[0-9]{4}-[0-9]{2}\.csv$"
+	-tag value
+	  	tag each Ledger journal entry with "key: value" given as "key:value", or with ":key:" given as "key"; may be repeated
+	-v	write each parsed transaction with all its fields to standard error e.g. to debug an input format
 
 See also [this package's README].
 
 [comma-separated values (CSV)]: https://en.wikipedia.org/wiki/Comma-separated_values
 [filters]: https://en.wikipedia.org/wiki/Filter_(software)
+[GnuCash]: https://www.gnucash.org
+[hledger]: https://hledger.org
 [Ledger]: https://ledger-cli.org
+[Open Financial Exchange]: https://en.wikipedia.org/wiki/Open_Financial_Exchange
 [this package's README]: https://github.com/arnhemcr/financial/tree/main
 */
 package main
 
-import (
-	"encoding/csv"
-	"errors"
-	"flag"
-	"fmt"
-	aft "github.com/arnhemcr/financial/transaction"
-	"io"
-	"log"
-	"os"
-	"slices"
-)
-
-// The configuration returned by parseFlags.
-type config struct {
-	currency       string
-	formatFileName string
-	outFormatName  string
-	thisAccount    string
-}
+import "github.com/arnhemcr/financial/internal/csv2trn"
 
 func main() {
-	log.SetPrefix("csv2trn: ")
-	log.SetFlags(0)
-
-	cfg := parseFlags()
-
-	if !aft.IsLedgerCurrency(cfg.currency) {
-		log.Fatalf("%v: not a Ledger currency", cfg.currency)
-	}
-
-	switch cfg.outFormatName {
-	case aft.Ledger, aft.ModuleCSV:
-		// This output format name is valid.
-	default:
-		log.Fatalf("%v: not an output format name", cfg.outFormatName)
-	}
-
-	var err error
-
-	inFormat := aft.NewModuleCSVRecordFormat()
-	if cfg.formatFileName != "" {
-		inFormat, err = aft.NewCSVRecordFormat(cfg.formatFileName)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-
-	if cfg.thisAccount == "" && inFormat.ThisAccountI == 0 {
-		log.Fatal("cannot get this account: CSV records do not contain that field and its flag is not set")
-	}
-
-	r := csv.NewReader(os.Stdin)
-	/*
-		The number of fields in a record is checked by aft.ParseCSV,
-		so disable the reader's check.
-	*/
-	r.FieldsPerRecord, r.ReuseRecord = -1, true
-
-	ts, err := parseCSVStatement(r, cfg, inFormat)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	stringTransactions(ts, os.Stdout, cfg.outFormatName)
-}
-
-/*
-ParseFlags returns this program's configuration parsed from command line flags.
-If help was requested, parseFlags writes this program's help text then exits.
-If the flags are invalid, this program exits with a non-zero status.
-*/
-func parseFlags() config {
-	var cfg config
-
-	flag.StringVar(&cfg.currency, "c", "",
-		fmt.Sprintf("Ledger currency e.g. %q or %q; overrides currency field from input", "$", "GBP"))
-	flag.StringVar(&cfg.formatFileName, "f", "", "name of file containing input CSV record format in XML")
-	flag.StringVar(&cfg.outFormatName, "o", aft.ModuleCSV,
-		fmt.Sprintf("output format name: Ledger journal entry %q or %q",
-			aft.Ledger, aft.ModuleCSV))
-	flag.StringVar(&cfg.thisAccount, "t", "", fmt.Sprintf(
-		"the Ledger name of this account e.g. %q%s",
-		"Assets:Current", "; overrides this account field from input"))
-
-	var help bool
-
-	flag.BoolVar(&help, "h", false, "write this help text then exit")
-
-	flag.Usage = usage
-	flag.Parse()
-
-	if help {
-		usage()
-		os.Exit(0)
-	}
-
-	return cfg
-}
-
-/*
-ParseCSVStatement reads a CSV account statement,
-parses a transaction's fields from those in the CSV record on each line
-then returns the transactions.
-If it fails to read the statement, parseCSVStatement returns an error.
-If it fails to parse a transaction, parseCSVStatement logs a warning then continues.
-*/
-func parseCSVStatement(r *csv.Reader, cfg config, crf aft.CSVRecordFormat) ([]aft.Transaction, error) {
-	var ts []aft.Transaction
-
-	for {
-		fs, err := r.Read()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if err != nil {
-			return ts, fmt.Errorf("parseCSVStatement: %w", err)
-		}
-
-		var t aft.Transaction
-
-		t.Currency, t.ThisAccount = cfg.currency, cfg.thisAccount
-
-		err = t.ParseCSV(fs, crf)
-		if err != nil {
-			n, _ := r.FieldPos(0)
-			log.Printf("%v on line %v", err, n)
-
-			continue
-		}
-
-		ts = append(ts, t)
-	}
-
-	return ts, nil
-}
-
-/*
-StringTransactions writes the transactions in the named format.
-It assumes the transactions are in date order ascending or descending.
-If the first transaction is later than the last one,
-stringTransactions reverses the order.
-*/
-func stringTransactions(ts []aft.Transaction, w *os.File, name string) {
-	n := len(ts)
-
-	tSeq := slices.All(ts)
-	if 2 <= n && ts[0].Date > ts[n-1].Date {
-		tSeq = slices.Backward(ts)
-	}
-
-	for _, t := range tSeq {
-		fmt.Fprint(w, t.StringFormat(name))
-	}
-}
-
-// Usage writes the help text for this program.
-func usage() {
-	fmt.Fprint(os.Stderr, `
-CSV2trn filters financial transactions from comma-separated values (CSV) records in an account statement
-to a selected format.
-
-A transaction is the transfer of an amount of currency between accounts on a particular day.
-It is described by a memo and code, also called the description and transaction type.
-A statement and its records belong to an account, which is called this account in transactions from those records.
-
-CSV2trn reads a statement from standard input.
-It parses each line as a transaction CSV record following an input format
-and warns about lines that cannot be parsed to standard error.
-The input format defaults to this module's CSV record (mcsv), but it is usually loaded from an XML file.
-In XML, the mcsv format is:
-
-    <CSVRecordFormat>
-        <NFields>7</NFields><!-- The number of fields in the record. -->
-
-        <!-- The index of each field: 1..NFields or zero if it is not provided. -->
-        <DateI>1</DateI>
-	    <DateLayout>2006-01-02</DateLayout><!-- The default Go date layout time.DateOnly. -->
-        <ThisAccountI>2</ThisAccountI>
-        <OtherAccountI>3</OtherAccountI>
-        <CodeI>4</CodeI>
-        <MemoI>5</MemoI>
-        <AmountI>6</AmountI>
-            <CreditI>0</CreditI>
-            <DebitI>0</DebitI>
-        <CurrencyI>7</CurrencyI>
-    </CSVRecordFormat>
-
-If the other account field is not provided then its default value is
-"Imbalance".
-
-CSV2trn orders transactions by date ascending and writes them to standard output
-in the selected format: Ledger journal entries (lent) or mcsv.
-
-Usage:
-
-	csv2trn [flags]
-
-The flags are:
-
-`)
-	flag.PrintDefaults()
-	fmt.Fprintln(os.Stderr)
+	csv2trn.Main()
 }
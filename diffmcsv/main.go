@@ -0,0 +1,57 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Diffmcsv compares two files of financial transactions in this module's [comma-separated values (CSV)] records
+e.g. the imports of one statement before and after a change to its input format,
+and reports the transactions added, removed and changed.
+
+Diffmcsv reads the old and new files named by its arguments, skipping any leading UTF-8 byte order mark (BOM).
+It parses each line as a transaction CSV record in this module's format (mcsv).
+If any line cannot be parsed, diffmcsv writes messages to standard error and exits with a non-zero status.
+
+Transactions are matched by their date, amount and memo, and each old transaction matches at most one new one.
+A matched pair whose other fields differ e.g. their other accounts is changed.
+Diffmcsv writes to standard output each removed transaction as its record prefixed by "- ",
+each changed pair as its old record prefixed by "< " followed by its new record prefixed by "> ",
+in the order of the old file, then each added transaction prefixed by "+ ", in the order of the new file.
+If there are any differences, diffmcsv exits with a non-zero status.
+
+Usage:
+
+	diffmcsv [flags] old new
+
+The flags are:
+
+	-h	write this help text then exit
+
+See also [this package's README].
+
+[comma-separated values (CSV)]: https://en.wikipedia.org/wiki/Comma-separated_values
+[this package's README]: https://github.com/arnhemcr/financial/tree/main
+*/
+package main
+
+import "github.com/arnhemcr/financial/internal/diffmcsv"
+
+func main() {
+	diffmcsv.Main()
+}
@@ -0,0 +1,125 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Fin runs this module's programs as its subcommands, so that one program can be installed instead of seven.
+
+Fin runs the subcommand named by its first argument with the rest of its arguments, which are that program's flags.
+The subcommands are:
+
+	diff        diffmcsv: compare this module's CSV records in two files, reporting changed transactions
+	import      csv2trn: filter transactions from a CSV statement to a selected format
+	journal     mcsv2lent: filter transactions from this module's CSV records to Ledger journal entries
+	merge       mrglent: merge Ledger journals into a general journal
+	merge-mcsv  mrgmcsv: merge this module's CSV records from several files into one
+	normalise   normlent: normalise the dates of a Ledger journal's entries to YYYY-MM-DD
+	split       splitlent: split a Ledger journal into one journal per account
+
+For example, "fin import -f NB.xml -c GBP" is the same as "csv2trn -f NB.xml -c GBP".
+Each subcommand's help text is written by flag -h e.g. "fin merge -h".
+
+Usage:
+
+	fin subcommand [flags]
+
+See also [this package's README].
+
+[this package's README]: https://github.com/arnhemcr/financial/tree/main
+*/
+package main
+
+import (
+	"fmt"
+	"github.com/arnhemcr/financial/internal/csv2trn"
+	"github.com/arnhemcr/financial/internal/diffmcsv"
+	"github.com/arnhemcr/financial/internal/mcsv2lent"
+	"github.com/arnhemcr/financial/internal/mrglent"
+	"github.com/arnhemcr/financial/internal/mrgmcsv"
+	"github.com/arnhemcr/financial/internal/normlent"
+	"github.com/arnhemcr/financial/internal/splitlent"
+	"log"
+	"os"
+)
+
+// The programs run by each subcommand name.
+var subcommands = map[string]func(){
+	"diff":       diffmcsv.Main,
+	"import":     csv2trn.Main,
+	"journal":    mcsv2lent.Main,
+	"merge":      mrglent.Main,
+	"merge-mcsv": mrgmcsv.Main,
+	"normalise":  normlent.Main,
+	"split":      splitlent.Main,
+}
+
+func main() {
+	log.SetPrefix("fin: ")
+	log.SetFlags(0)
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+
+	switch name {
+	case "-h", "-help", "--help", "help":
+		usage()
+		os.Exit(0)
+	}
+
+	run, found := subcommands[name]
+	if !found {
+		log.Fatalf("%v: not a subcommand; see \"fin -h\"", name)
+	}
+
+	// The subcommand parses its flags from the command line, so remove the subcommand's name from it.
+	os.Args = append([]string{os.Args[0] + " " + name}, os.Args[2:]...)
+
+	run()
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Fin runs this module's programs as its subcommands, so that one program can be installed instead of seven.
+
+Fin runs the subcommand named by its first argument with the rest of its arguments, which are that program's flags.
+The subcommands are:
+
+	diff        diffmcsv: compare this module's CSV records in two files, reporting changed transactions
+	import      csv2trn: filter transactions from a CSV statement to a selected format
+	journal     mcsv2lent: filter transactions from this module's CSV records to Ledger journal entries
+	merge       mrglent: merge Ledger journals into a general journal
+	merge-mcsv  mrgmcsv: merge this module's CSV records from several files into one
+	normalise   normlent: normalise the dates of a Ledger journal's entries to YYYY-MM-DD
+	split       splitlent: split a Ledger journal into one journal per account
+
+For example, "fin import -f NB.xml -c GBP" is the same as "csv2trn -f NB.xml -c GBP".
+Each subcommand's help text is written by flag -h e.g. "fin merge -h".
+
+Usage:
+
+	fin subcommand [flags]
+
+`)
+}
@@ -0,0 +1,1039 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package csv2trn implements this module's program csv2trn, which is also subcommand "import" of program fin.
+See the program's documentation for its behaviour.
+*/
+package csv2trn
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The output format name for writing only the number of transactions.
+const countOnly = "none"
+
+// The configuration returned by parseFlags.
+type config struct {
+	against       string // The name of the Ledger journal file whose transactions are not written.
+	allowEmpty    bool
+	amountColumn  int
+	assertBalance bool
+	cleanMemo     bool
+	clearedUntil  string
+	crlf          bool
+	currency      string
+	decimalPlaces int
+	declare       bool
+	dumpFormat    bool
+	excludeCodes  string
+	explicitPlus  bool
+	fieldsSpec    string
+	formatNames   []string
+	includeCodes  string
+	order         string
+	outDateLayout string
+	outFormatName string
+	rounding      string
+	rulesFileName string
+	sort          bool
+	source        string
+	splitTemplate string
+	strict        bool
+	tags          map[string]string
+	thisAccount   string
+	thisAccountRE *regexp.Regexp // The regular expression deriving this account from a statement's file name.
+	verbose       bool
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("csv2trn: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+
+	if !aft.IsLedgerCurrency(cfg.currency) {
+		log.Fatalf("%v: not a Ledger currency", cfg.currency)
+	}
+
+	switch cfg.outFormatName {
+	case aft.Hledger, aft.Ledger, aft.ModuleCSV, aft.ModuleCSVSummary, aft.OFX, aft.GnuCash, aft.Prices, aft.JSON,
+		countOnly:
+		// This output format name is valid.
+	default:
+		log.Fatalf("%v: not an output format name", cfg.outFormatName)
+	}
+
+	if !aft.IsDateLayout(cfg.outDateLayout) {
+		log.Fatalf("output date layout must be Go-style e.g. %q", time.DateOnly)
+	}
+
+	aft.OutputDateLayout = cfg.outDateLayout
+	aft.ExplicitPlus = cfg.explicitPlus
+
+	if !aft.IsRounding(cfg.rounding) {
+		log.Fatalf("%v: not a rounding mode", cfg.rounding)
+	}
+
+	aft.OutputDecimalPlaces, aft.OutputRounding = cfg.decimalPlaces, cfg.rounding
+
+	if cfg.amountColumn < 0 {
+		log.Fatalf("%v: amount column cannot be negative", cfg.amountColumn)
+	}
+
+	aft.LedgerAmountColumn = cfg.amountColumn
+
+	if cfg.declare && cfg.outFormatName != aft.Ledger && cfg.outFormatName != aft.Hledger {
+		log.Fatalf("cannot declare accounts: output format must be %q or %q", aft.Ledger, aft.Hledger)
+	}
+
+	if cfg.splitTemplate != "" {
+		if strings.Contains(fmt.Sprintf(cfg.splitTemplate, ""), "%!") {
+			log.Fatalf("%v: file name template must contain one verb e.g. %q", cfg.splitTemplate, "%v.journal")
+		}
+
+		if cfg.outFormatName == countOnly {
+			log.Fatalf("cannot split output: output format cannot be %q", countOnly)
+		}
+	}
+
+	switch cfg.order {
+	case aft.OrderAuto, aft.OrderAscending, aft.OrderDescending, aft.OrderKeep:
+		// This order name is valid.
+	default:
+		log.Fatalf("%v: not an order name", cfg.order)
+	}
+
+	var err error
+
+	inFormats := []aft.CSVRecordFormat{aft.NewModuleCSVRecordFormat()}
+	if len(cfg.formatNames) != 0 {
+		inFormats = make([]aft.CSVRecordFormat, len(cfg.formatNames))
+	}
+
+	for i, n := range cfg.formatNames {
+		inFormats[i], err = aft.LoadCSVRecordFormat(n)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if cfg.fieldsSpec != "" {
+		f, err := aft.ParseCSVRecordFormatSpec(cfg.fieldsSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(cfg.formatNames) == 0 {
+			inFormats = nil
+		}
+
+		inFormats = append(inFormats, f)
+		cfg.formatNames = append(cfg.formatNames, "-fields")
+	}
+
+	for i := range inFormats {
+		if cfg.cleanMemo {
+			inFormats[i].CleanMemo = true
+		}
+	}
+
+	var rules aft.Rules
+
+	if cfg.rulesFileName != "" {
+		rules, err = aft.LoadRules(cfg.rulesFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if cfg.dumpFormat {
+		for _, f := range inFormats {
+			err = f.WriteXML(os.Stdout)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		os.Exit(0)
+	}
+
+	if cfg.clearedUntil != "" {
+		cfg.clearedUntil, err = aft.ParseModuleDate(cfg.clearedUntil)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var ts []aft.Transaction
+
+	if flag.NArg() == 0 {
+		ts = translate(os.Stdin, "", inFormats, cfg)
+	}
+
+	for _, arg := range flag.Args() {
+		fn, a := splitStatementArg(arg)
+
+		fcfg := cfg
+		if a == "" && cfg.thisAccount == "" && cfg.thisAccountRE != nil {
+			a = accountFromName(cfg.thisAccountRE, fn)
+		}
+
+		if a != "" {
+			fcfg.thisAccount = a
+		}
+
+		ts = append(ts, translateFile(fn, inFormats, fcfg)...)
+	}
+
+	applyRules(ts, rules, cfg)
+
+	if cfg.clearedUntil != "" {
+		markStatus(ts, cfg.clearedUntil)
+	}
+
+	ts = filterCodes(ts, splitList(cfg.includeCodes), splitList(cfg.excludeCodes))
+
+	if cfg.against != "" {
+		ts = excludeExisting(ts, loadJournal(cfg.against, cfg.outDateLayout))
+	}
+
+	if cfg.sort {
+		aft.SortTransactions(ts)
+	}
+
+	keepLastBalances(ts, cfg.assertBalance)
+
+	if cfg.splitTemplate == "" {
+		err = writeTransactions(os.Stdout, ts, cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	as, a2ts := splitTransactions(ts)
+	for _, a := range as {
+		f, err := os.Create(fmt.Sprintf(cfg.splitTemplate, a))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = writeTransactions(f, a2ts[a], cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+/*
+ApplyRules adjusts the transactions according to the rules, then sets the tags from the configuration.
+The currency from flag -c is set only in transactions still without one, so that currency rules take precedence.
+*/
+func applyRules(ts []aft.Transaction, rules aft.Rules, cfg config) {
+	for i := range ts {
+		rules.Apply(&ts[i])
+
+		if ts[i].Currency == "" {
+			ts[i].Currency = cfg.currency
+		}
+
+		if cfg.source != "" {
+			ts[i].SetTag("source", cfg.source)
+		}
+
+		for k, v := range cfg.tags {
+			ts[i].SetTag(k, v)
+		}
+	}
+}
+
+/*
+TranslateFile returns the transactions of the statement in the named file, as translate does.
+A file with extension ".gz" is decompressed first.
+A file with extension ".zip" is an archive of statements, whose members are translated in name order
+and their transactions concatenated; messages about a member are prefixed by the archive and member names.
+If the file cannot be read, this program exits with a non-zero status.
+*/
+func translateFile(fileName string, inFormats []aft.CSVRecordFormat, cfg config) []aft.Transaction {
+	if strings.EqualFold(filepath.Ext(fileName), ".zip") {
+		return translateZip(fileName, inFormats, cfg)
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+
+	if strings.EqualFold(filepath.Ext(fileName), ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			log.Fatalf("%v: %v", fileName, err)
+		}
+		defer zr.Close()
+
+		r = zr
+	}
+
+	return translate(r, fileName, inFormats, cfg)
+}
+
+/*
+TranslateZip returns the transactions of the statements in the named zip archive, as translateFile does.
+Directories in the archive are skipped.
+*/
+func translateZip(fileName string, inFormats []aft.CSVRecordFormat, cfg config) []aft.Transaction {
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		log.Fatalf("%v: %v", fileName, err)
+	}
+	defer zr.Close()
+
+	zfs := slices.SortedFunc(slices.Values(zr.File), func(a, b *zip.File) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var ts []aft.Transaction
+
+	for _, zf := range zfs {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		name := fileName + ":" + zf.Name
+
+		r, err := zf.Open()
+		if err != nil {
+			log.Fatalf("%v: %v", name, err)
+		}
+
+		ts = append(ts, translate(r, name, inFormats, cfg)...)
+
+		r.Close()
+	}
+
+	return ts
+}
+
+/*
+AccountFromName returns the Ledger account derived from the base of the file name by the regular expression:
+its first parenthesised subexpression, or else whole match, with dashes replaced by colons
+e.g. "Assets:Current" from "Assets-Current-2025-05.csv".
+If the name does not match, accountFromName returns the empty string.
+*/
+func accountFromName(re *regexp.Regexp, fileName string) string {
+	m := re.FindStringSubmatch(filepath.Base(fileName))
+	if m == nil {
+		return ""
+	}
+
+	a := m[0]
+	if 1 < len(m) {
+		a = m[1]
+	}
+
+	return strings.ReplaceAll(a, "-", ":")
+}
+
+/*
+SplitStatementArg returns the file name and this account of a statement argument e.g. "jan.csv:Assets:Current".
+This account follows the first colon, if any, unless the whole argument names an existing file.
+If the argument has no this account, splitStatementArg returns it as the file name and empty string.
+*/
+func splitStatementArg(arg string) (string, string) {
+	_, err := os.Stat(arg)
+	if err == nil {
+		return arg, ""
+	}
+
+	fn, a, found := strings.Cut(arg, ":")
+	if !found {
+		return arg, ""
+	}
+
+	return fn, a
+}
+
+/*
+WriteTransactions writes the transactions to w in the output format of the configuration,
+preceded by their account directives if they are to be declared.
+If it fails to write, writeTransactions returns the error.
+*/
+func writeTransactions(w io.Writer, ts []aft.Transaction, cfg config) error {
+	if cfg.crlf {
+		w = aft.NewCRLFWriter(w)
+	}
+
+	if cfg.outFormatName == countOnly {
+		_, err := fmt.Fprintln(w, len(ts))
+
+		return err
+	}
+
+	if cfg.declare {
+		_, err := fmt.Fprint(w, aft.StringLedgerAccounts(ts))
+		if err != nil {
+			return err
+		}
+	}
+
+	return aft.WriteTransactions(w, ts, cfg.outFormatName)
+}
+
+/*
+SplitTransactions groups the transactions by this account, keeping their order.
+It returns the accounts in the order they were first found and the transactions for each account.
+*/
+func splitTransactions(ts []aft.Transaction) ([]string, map[string][]aft.Transaction) {
+	a2ts := make(map[string][]aft.Transaction)
+
+	var as []string
+
+	for _, t := range ts {
+		_, found := a2ts[t.ThisAccount]
+		if !found {
+			as = append(as, t.ThisAccount)
+		}
+
+		a2ts[t.ThisAccount] = append(a2ts[t.ThisAccount], t)
+	}
+
+	return as, a2ts
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.StringVar(&cfg.against, "against", "",
+		"name of Ledger journal file; write only transactions not already in it e.g. to append to it")
+	flag.BoolVar(&cfg.allowEmpty, "allow-empty", false,
+		"allow a statement that is not empty to have no transactions e.g. only a header, rather than exit with an error")
+	flag.IntVar(&cfg.amountColumn, "amount-column", 0,
+		"column at which amounts in Ledger journal entries end e.g. 48 to align them, or 0 for none")
+	flag.BoolVar(&cfg.assertBalance, "assert-balance", false,
+		"assert the balance field of the last transaction of each account in its Ledger journal entry")
+	flag.BoolVar(&cfg.cleanMemo, "clean-memo", false,
+		"trim memos and collapse repeated white space in them, as does the input format's CleanMemo")
+	flag.StringVar(&cfg.clearedUntil, "cleared-until", "",
+		"mark transactions on or before this date YYYY-MM-DD as cleared and the rest as pending in Ledger")
+	flag.BoolVar(&cfg.crlf, "crlf", false, "end output lines with carriage return and line feed e.g. for Windows")
+	flag.StringVar(&cfg.currency, "c", "",
+		fmt.Sprintf("Ledger currency e.g. %q or %q; used when the currency field from input is empty", "$", "GBP"))
+	flag.IntVar(&cfg.decimalPlaces, "decimal-places", -1,
+		"number of decimal places in which amounts are written e.g. 2, or -1 for as many as each needs")
+	flag.BoolVar(&cfg.declare, "declare-accounts", false,
+		"precede Ledger journal entries with an account directive for each of their accounts")
+	flag.BoolVar(&cfg.dumpFormat, "dump-format", false, "write the input CSV record format in XML then exit")
+	flag.StringVar(&cfg.excludeCodes, "exclude-codes", "",
+		"comma-separated list of transaction codes to exclude e.g. \"INT,FEE\"")
+	flag.BoolVar(&cfg.explicitPlus, "explicit-plus", false,
+		"write positive amounts with a plus sign e.g. \"+162\" to keep the sign of every amount when comparing exports")
+	flag.Func("f", fmt.Sprintf("name of built-in input CSV record format e.g. %q, or of file containing it "+
+		"in XML or JSON; may be repeated to detect each statement's format", aft.ModuleCSV), func(s string) error {
+		cfg.formatNames = append(cfg.formatNames, s)
+
+		return nil
+	})
+	flag.StringVar(&cfg.fieldsSpec, "fields", "",
+		"input CSV record format given inline as key=value pairs e.g. "+
+			"\"date=2,memo=3,amount=15,dateLayout=02-01-2006,nfields=16\"; detected like another -f")
+	flag.StringVar(&cfg.includeCodes, "include-codes", "",
+		"comma-separated list of transaction codes to include; all are included if the list is empty")
+	flag.StringVar(&cfg.outDateLayout, "odate", time.DateOnly,
+		"Go date layout of output dates e.g. \"01/02/2006\" for Ledger entries, mcsv and GnuCash records")
+	flag.StringVar(&cfg.order, "order", aft.OrderAuto, fmt.Sprintf(
+		"order of transactions in the statement: %q detected from first and last dates, %q, %q or %q as read",
+		aft.OrderAuto, aft.OrderAscending, aft.OrderDescending, aft.OrderKeep))
+	flag.StringVar(&cfg.outFormatName, "o", aft.ModuleCSV,
+		fmt.Sprintf("output format name: Ledger journal entry %q, hledger journal entry %q, %q, "+
+			"%q with monthly subtotals, OFX document %q, GnuCash import CSV %q, Ledger price directive %q, "+
+			"JSON array %q or %q to write only the number of transactions",
+			aft.Ledger, aft.Hledger, aft.ModuleCSV, aft.ModuleCSVSummary, aft.OFX, aft.GnuCash, aft.Prices, aft.JSON,
+			countOnly))
+	flag.StringVar(&cfg.rounding, "rounding", aft.RoundHalfEven, fmt.Sprintf(
+		"rounding mode of amounts with more decimal places than flag -decimal-places: %q or %q away from zero",
+		aft.RoundHalfEven, aft.RoundHalfUp))
+	flag.StringVar(&cfg.rulesFileName, "r", "", "name of file containing rules in XML e.g. for splitting fees")
+	flag.BoolVar(&cfg.sort, "sort", false, "sort transactions by date, keeping the order of those with the same date")
+	flag.StringVar(&cfg.source, "source", "",
+		"name of the statement e.g. its file name; tags each Ledger journal entry as its source")
+	flag.StringVar(&cfg.splitTemplate, "split", "", fmt.Sprintf("file name template e.g. %q for writing "+
+		"each account's transactions to its own file, rather than standard output; %q is replaced by this account",
+		"%v.journal", "%v"))
+	flag.BoolVar(&cfg.strict, "strict", false,
+		"exit with a non-zero status, after writing warnings, if any line cannot be parsed")
+	flag.Func("tag", "tag each Ledger journal entry with \"key: value\" given as \"key:value\", "+
+		"or with \":key:\" given as \"key\"; may be repeated", func(s string) error {
+		k, v, _ := strings.Cut(s, ":")
+		if !aft.IsTagKey(k) {
+			return errTagKey
+		}
+
+		if cfg.tags == nil {
+			cfg.tags = make(map[string]string)
+		}
+
+		cfg.tags[k] = strings.TrimSpace(v)
+
+		return nil
+	})
+	flag.StringVar(&cfg.thisAccount, "t", "", fmt.Sprintf(
+		"the Ledger name of this account e.g. %q%s",
+		"Assets:Current", "; overrides this account field from input"))
+	flag.Func("t-from-name", "regular expression deriving this account from each statement's file name, "+
+		"if flag -t is not set, with dashes replaced by colons e.g. \"^(.*)-[0-9]{4}-[0-9]{2}\\.csv$\"",
+		func(s string) error {
+			var err error
+
+			cfg.thisAccountRE, err = regexp.Compile(s)
+
+			return err
+		})
+
+	flag.BoolVar(&cfg.verbose, "v", false,
+		"write each parsed transaction with all its fields to standard error e.g. to debug an input format")
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+var errTagKey = errors.New("tag key cannot be empty string or contain white space or a colon")
+
+/*
+Translate reads a statement from the reader and returns its transactions in date order ascending,
+as set by flag -order.
+If there are several input formats, the statement's format is detected.
+The statement's name, if not empty string, prefixes messages about it.
+If the statement cannot be translated, or no transactions are parsed from a statement that is not empty
+and that is not allowed, this program exits with a non-zero status.
+*/
+func translate(r io.Reader, name string, inFormats []aft.CSVRecordFormat, cfg config) []aft.Transaction {
+	prefix := ""
+	if name != "" {
+		prefix = name + ": "
+	}
+
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inFormat := inFormats[0]
+
+	if 1 < len(inFormats) {
+		i, err := aft.DetectCSVRecordFormat(bs, inFormats)
+		if err != nil {
+			log.Fatalf("%v%v", prefix, err)
+		}
+
+		inFormat = inFormats[i]
+
+		if cfg.verbose {
+			log.Printf("%vdetected input format %v", prefix, cfg.formatNames[i])
+		}
+	}
+
+	thisAccount := cfg.thisAccount
+	if thisAccount == "" && !inFormat.ContainsThisAccount() {
+		thisAccount = inFormat.ThisAccountName
+	}
+
+	if thisAccount == "" && !inFormat.ContainsThisAccount() {
+		log.Fatalf("%vcannot get this account: CSV records do not contain that field, "+
+			"its flag is not set and the input format does not name it", prefix)
+	}
+
+	var parsed func(aft.Transaction, int)
+
+	if cfg.verbose {
+		parsed = func(t aft.Transaction, line int) {
+			log.Printf("%vline %v: parsed %v", prefix, line, stringFields(t))
+		}
+	}
+
+	// The currency from flag -c is set after rules are applied.
+	ts, err := aft.TranslateCSVContext(context.Background(), bytes.NewReader(bs), inFormat, thisAccount, "", parsed)
+
+	logErrors(err, prefix, cfg.strict)
+
+	// A statement with records but no transactions usually means its input format is wrong.
+	if len(ts) == 0 && len(bytes.TrimSpace(bytes.TrimPrefix(bs, []byte("\uFEFF")))) != 0 && !cfg.allowEmpty {
+		log.Fatalf("%vno transactions parsed from statement; check the input format or set flag -allow-empty", prefix)
+	}
+
+	err = aft.OrderTransactions(ts, cfg.order)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return ts
+}
+
+/*
+StringFields returns the transaction with all its fields e.g. for debugging an input format,
+unlike its String method, which leaves most of them out.
+The balance is written as its value, or "<nil>" if there is none, rather than as a pointer.
+*/
+func stringFields(t aft.Transaction) string {
+	b := "<nil>"
+	if t.Balance != nil {
+		b = fmt.Sprint(*t.Balance)
+	}
+
+	return fmt.Sprintf("{Amount:%v Balance:%v Code:%v Currency:%v Date:%v Fee:%v FeeAccount:%v Memo:%v Note:%v "+
+		"OtherAccount:%v Payee:%v Price:%v Status:%v ThisAccount:%v Time:%v Tags:%v}",
+		t.Amount, b, t.Code, t.Currency, t.Date, t.Fee, t.FeeAccount, t.Memo, t.Note,
+		t.OtherAccount, t.Payee, t.Price, t.Status, t.ThisAccount, t.Time, t.Tags)
+}
+
+/*
+LoadJournal returns the transactions of the dated entries in the named Ledger journal file,
+including those marked as mirrors, which are as much in the journal as any other.
+An entry that cannot be parsed as a transaction is skipped, and a message is written to standard error.
+If the file cannot be read, this program exits with a non-zero status.
+*/
+func loadJournal(fileName, dateLayout string) []aft.Transaction {
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	ts, err := aft.ParseAllLedgerJournal(f, dateLayout)
+	if err == nil {
+		return ts
+	}
+
+	// Errors for entries that cannot be parsed are joined, unlike one for failing to read the journal.
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		log.Fatalf("%v: %v", fileName, err)
+	}
+
+	for _, e := range j.Unwrap() {
+		log.Printf("%v: ignoring %v", fileName, e)
+	}
+
+	return ts
+}
+
+/*
+ExcludeExisting returns the transactions without those already in the existing ones:
+those with the same date, this account, amount and currency as an existing transaction.
+Each existing transaction excludes at most one transaction, so repeated transactions on a day
+e.g. two coffees are only excluded as often as they exist.
+*/
+func excludeExisting(ts, existing []aft.Transaction) []aft.Transaction {
+	byDate := make(map[string][]aft.Transaction)
+	for _, e := range existing {
+		byDate[e.Date] = append(byDate[e.Date], e)
+	}
+
+	return slices.DeleteFunc(ts, func(t aft.Transaction) bool {
+		es := byDate[t.Date]
+
+		i := slices.IndexFunc(es, func(e aft.Transaction) bool {
+			return e.ThisAccount == t.ThisAccount && e.Currency == t.Currency &&
+				math.Abs(e.Amount-t.Amount) <= aft.AmountTolerance
+		})
+		if i < 0 {
+			return false
+		}
+
+		byDate[t.Date] = slices.Delete(es, i, i+1)
+
+		return true
+	})
+}
+
+/*
+FilterCodes returns the transactions whose codes are on the include list, unless it is empty,
+and not on the exclude list.
+*/
+func filterCodes(ts []aft.Transaction, include, exclude []string) []aft.Transaction {
+	return slices.DeleteFunc(ts, func(t aft.Transaction) bool {
+		return (len(include) != 0 && !slices.Contains(include, t.Code)) || slices.Contains(exclude, t.Code)
+	})
+}
+
+/*
+LogErrors logs each of the errors joined in err, with the prefix.
+A [aft.LineError] is logged as a warning, unless strict is true,
+while any other error is fatal and this program exits with a non-zero status.
+Records with the wrong number of fields, which usually means the input format is wrong,
+are warned about once rather than once each, as by nFieldsWarning.
+If strict is true, this program exits with a non-zero status after logging any errors.
+*/
+func logErrors(err error, prefix string, strict bool) {
+	if err == nil {
+		return
+	}
+
+	errs := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = j.Unwrap()
+	}
+
+	w, errs := nFieldsWarning(errs)
+	if w != "" {
+		log.Printf("%v%v", prefix, w)
+	}
+
+	for _, e := range errs {
+		var le aft.LineError
+		if !errors.As(e, &le) {
+			log.Fatalf("%v%v", prefix, e)
+		}
+
+		log.Printf("%v%v", prefix, le)
+	}
+
+	if strict {
+		os.Exit(1)
+	}
+}
+
+/*
+NFieldsWarning returns one warning for all the errors for records with the wrong number of fields, if there are
+at least two wherever they are among the errors, and the other errors.
+The warning gives the first such record's line and its number of fields e.g.
+"input format expects 7 fields but line 3 has 5; skipped 12 lines with the wrong number".
+Otherwise, nFieldsWarning returns the empty string and the errors unchanged.
+*/
+func nFieldsWarning(errs []error) (string, []error) {
+	var (
+		first  aft.LineError
+		nf     aft.NFieldsError
+		n      int
+		others []error // The errors other than those for records with the wrong number of fields.
+	)
+
+	for _, e := range errs {
+		var (
+			le  aft.LineError
+			nfe aft.NFieldsError
+		)
+
+		if !errors.As(e, &nfe) {
+			others = append(others, e)
+
+			continue
+		}
+
+		if n == 0 {
+			_ = errors.As(e, &le)
+			first, nf = le, nfe
+		}
+
+		n++
+	}
+
+	if n < 2 {
+		return "", errs
+	}
+
+	return fmt.Sprintf("input format expects %v fields but line %v has %v; skipped %v lines with the wrong number",
+		nf.NFields, first.Line, nf.Found, n), others
+}
+
+/*
+KeepLastBalances removes the balance from each transaction, except from the last transaction of each account,
+if assert is true, so that only its Ledger journal entry asserts the balance.
+*/
+func keepLastBalances(ts []aft.Transaction, assert bool) {
+	last := make(map[string]int)
+
+	for i, t := range ts {
+		if assert && t.Balance != nil {
+			last[t.ThisAccount] = i
+		}
+	}
+
+	for i := range ts {
+		if j, found := last[ts[i].ThisAccount]; !found || i != j {
+			ts[i].Balance = nil
+		}
+	}
+}
+
+/*
+MarkStatus marks the transactions dated on or before the reconciliation date as cleared
+and the rest as pending.
+*/
+func markStatus(ts []aft.Transaction, clearedUntil string) {
+	for i := range ts {
+		if ts[i].Date <= clearedUntil {
+			ts[i].Status = aft.Cleared
+		} else {
+			ts[i].Status = aft.Pending
+		}
+	}
+}
+
+// SplitList returns the items in the comma-separated list, which is empty if the list is empty string.
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+
+	return strings.Split(list, ",")
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+CSV2trn filters financial transactions from comma-separated values (CSV) records in an account statement
+to a selected format.
+
+A transaction is the transfer of an amount of currency between accounts on a particular day.
+It is described by a memo and code, also called the description and transaction type.
+A statement and its records belong to an account, which is called this account in transactions from those records.
+This account is given by a statement's suffix, flag -t, a field in the records or, failing those,
+the input format's this account name.
+A statement file name may be followed by a colon and its this account e.g. "jan.csv:Assets:Current",
+so that one run imports statements from several accounts.
+Alternatively, if flag -t is not set, flag -t-from-name derives this account from each statement's file name
+by a regular expression whose first parenthesised subexpression, or else whole match, is the account
+with its dashes replaced by colons e.g. "^(.*)-[0-9]{4}-[0-9]{2}\.csv$" for "Assets-Current-2025-05.csv".
+A file name that does not match leaves this account to the other sources.
+
+CSV2trn reads statements from the named files or, if there are none, one statement from standard input.
+A file with extension ".gz" is decompressed with gzip.
+A file with extension ".zip" is an archive of statements e.g. a year of monthly statements,
+whose members are read in name order, with messages about a member prefixed by the archive and member names.
+It skips any leading UTF-8 byte order mark (BOM) in a statement.
+It parses each line as a transaction CSV record following an input format
+and warns about lines that cannot be parsed to standard error.
+With flag -strict, such a line instead causes csv2trn to exit with a non-zero status without writing transactions.
+If no transactions are parsed from a statement that is not empty, which usually means its input format is wrong,
+csv2trn exits with a non-zero status, unless flag -allow-empty is set.
+The input format defaults to this module's CSV record (mcsv), but it is usually loaded from an XML file,
+or a JSON file with extension ".json" whose object has the same members as the XML elements.
+An input format with columns is for statements of fixed-width lines, rather than CSV records,
+whose fields are at those character positions.
+An input format with column names maps its fields to the names of columns in the statement's header record,
+rather than giving their indexes, so that it still works when a bank adds or reorders columns.
+Formats built into csv2trn, such as mcsv, can be selected by name instead.
+If flag -f is repeated, the format of each statement is detected:
+it is the first format whose header matches the statement's first record or, failing that,
+the first format that parses the most records e.g. because its number of fields is right.
+For a quick one-off or to explore an unknown statement, flag -fields gives a format inline
+as key=value pairs e.g. "date=2,memo=3,amount=15,dateLayout=02-01-2006,nfields=16",
+whose keys are the XML element names below, case insensitive and without the final I of field indexes.
+Flag -dump-format writes the input format in XML, which is a starting point for a new format.
+In XML, the mcsv format is:
+
+    <CSVRecordFormat>
+        <NFields>7</NFields><!-- The number of fields in the record. -->
+            <Header></Header><!-- Optional first record e.g. "Date,Memo,Amount", which is skipped. -->
+            <LazyQuotes>false</LazyQuotes><!-- Whether quotes inside unquoted fields are allowed. -->
+            <CommentChar></CommentChar><!-- Optional e.g. "#" for lines of metadata, which are skipped. -->
+            <Columns><!-- Optional character positions of each field, if records are fixed width. -->
+                <Column><Start>1</Start><End>10</End></Column>
+            </Columns>
+            <ExactNFields>false</ExactNFields><!-- Whether extra trailing empty fields are rejected. -->
+            <IgnoreLeadingColumns>0</IgnoreLeadingColumns><!-- Optional e.g. 1 for a row number dropped from records. -->
+            <ColumnNames><!-- Optional header names of columns, whose indexes are found from each statement. -->
+                <ColumnName><Field>DateI</Field><Name>Transaction Date</Name></ColumnName>
+            </ColumnNames>
+
+        <!-- The index of each field: 1..NFields or zero if it is not provided. -->
+        <DateI>1</DateI>
+            <DateLayout>2006-01-02</DateLayout><!-- The default Go date layout time.DateOnly. -->
+            <DateLayouts><!-- Optional further layouts tried in order if a date does not match. -->
+                <DateLayout>02/01/2006</DateLayout>
+            </DateLayouts>
+            <MonthNames><!-- Optional local month names substituted before dates are parsed. -->
+                <MonthName><Local>janvier</Local><English>January</English></MonthName>
+            </MonthNames>
+        <ThisAccountI>2</ThisAccountI>
+            <ThisAccountName></ThisAccountName><!-- Optional this account if the records do not contain it. -->
+            <NormaliseAccountNumbers>false</NormaliseAccountNumbers><!-- Whether account fields are numbers e.g. IBANs to normalise. -->
+        <OtherAccountI>3</OtherAccountI>
+        <CodeI>4</CodeI>
+            <CreditCode></CreditCode><!-- Optional codes e.g. "CR" and "DR" if the code index is zero. -->
+            <DebitCode></DebitCode>
+        <MemoI>5</MemoI>
+            <Memo2I>0</Memo2I><!-- Optional continuations of the memo e.g. "Narrative2", joined by the separator. -->
+            <Memo3I>0</Memo3I>
+                <MemoSeparator></MemoSeparator><!-- Optional e.g. " / "; a single space by default. -->
+            <MemoReplacements><!-- Optional regular expressions replaced in order in memos. -->
+                <MemoReplacement><Pattern>^POS PURCHASE [0-9]+ </Pattern><Replacement></Replacement></MemoReplacement>
+            </MemoReplacements>
+            <CleanMemo>false</CleanMemo><!-- Whether repeated spaces in memos are collapsed. -->
+        <NoteI>0</NoteI><!-- An optional annotation written as a Ledger comment. -->
+        <PayeeI>0</PayeeI><!-- An optional payee written as the Ledger payee, with the memo as metadata. -->
+        <ReferenceI>0</ReferenceI><!-- An optional bank reference kept as Ledger tag "ref" e.g. "; ref: 18832946". -->
+        <AmountI>6</AmountI>
+            <SignI>0</SignI><!-- An optional "CR" or "DR" sign of an unsigned amount. -->
+            <CreditI>0</CreditI>
+            <DebitI>0</DebitI>
+                <SignedDebit>false</SignedDebit><!-- Whether debits may have a minus sign. -->
+                <SignedCreditDebit>false</SignedCreditDebit><!-- Whether a minus sign reverses a credit or debit. -->
+            <BalanceI>0</BalanceI><!-- An optional balance of this account after the transaction. -->
+            <AllowZeroAmount>false</AllowZeroAmount><!-- Whether zero amounts e.g. "0.00" are accepted. -->
+            <MaxDecimalPlaces>0</MaxDecimalPlaces><!-- Optional e.g. 2; an amount with more is reported. -->
+            <MinorUnitDigits>0</MinorUnitDigits><!-- Optional e.g. 2 if amounts are in cents e.g. "16200". -->
+        <CurrencyI>7</CurrencyI>
+            <StrictCurrency>false</StrictCurrency><!-- Whether currencies must be a symbol or code e.g. "GBP". -->
+            <CurrencyInAmount>false</CurrencyInAmount><!-- Whether amounts may contain currencies e.g. "NZD $162.00". -->
+            <PriceI>0</PriceI><!-- An optional price of one unit of the currency e.g. "0.85 GBP". -->
+
+        <!-- The separators in amount, credit and debit fields. -->
+        <DecimalSeparator>.</DecimalSeparator><!-- The default. -->
+        <ThousandsSeparator></ThousandsSeparator><!-- Optional e.g. "," for "1,234.56". -->
+    </CSVRecordFormat>
+
+If the other account field is not provided then its default value is
+"Imbalance".
+
+Rules, which are loaded from an XML file, adjust transactions after they are parsed.
+An invert rule negates the amounts in transactions belonging to its this account,
+for statements whose sign convention is the opposite of Ledger's e.g. for a credit card.
+A currency rule sets the currency of transactions belonging to its this account that have none,
+so that statements from accounts in different currencies can be translated together.
+Currency rules take precedence over flag -c, whose currency is set only in transactions still without one.
+An opening rule replaces the default other account in transactions with its code e.g. "OPEN" for opening balances
+by its account, which defaults to "Equity:Opening Balances".
+An other account rule replaces the default other account "Imbalance" in transactions belonging to its this account.
+A fee rule splits a fee, either a fraction or a fixed amount, from a transaction whose memo matches a regular expression.
+In Ledger journal entries, the fee is posted to the rule's account.
+A tag rule tags transactions whose memo matches a regular expression, or all of them if it is empty,
+which is written as Ledger metadata e.g. "; imported: 2026-10-15" or a tag e.g. "; :reconciled:". For example:
+
+    <Rules>
+        <Invert>
+            <ThisAccount>Liabilities:CreditCard</ThisAccount>
+        </Invert>
+        <Currency>
+            <ThisAccount>Assets:Euro</ThisAccount>
+            <Currency>EUR</Currency>
+        </Currency>
+        <Opening>
+            <Code>OPEN</Code>
+            <Account>Equity:Opening Balances</Account><!-- Optional. -->
+        </Opening>
+        <OtherAccount>
+            <ThisAccount>Assets:Current</ThisAccount>
+            <Account>Expenses:Unknown:Current</Account>
+        </OtherAccount>
+        <Fee>
+            <Memo> EUR$</Memo>
+            <Account>Expenses:Fees</Account>
+            <Fraction>0.015</Fraction><!-- Or a fixed <Amount>. -->
+        </Fee>
+        <Tag>
+            <Key>imported</Key>
+            <Value>2026-10-15</Value><!-- Optional; a tag may have a <Memo> too. -->
+        </Tag>
+    </Rules>
+
+CSV2trn orders each statement's transactions by date ascending, by reversing them if it is in descending order,
+and writes them to standard output in the selected format: Ledger journal entries (lent), hledger journal entries, mcsv,
+an Open Financial Exchange (OFX) document, GnuCash transaction import CSV or a JSON array.
+Format prices writes a Ledger price directive e.g. "P 1982-10-03 EUR 0.85 GBP"
+for each transaction with a price field and currency, which starts a Ledger price database.
+Format mcsv-summary follows the mcsv records with a subtotal record for each month and currency
+e.g. "1982-10,subtotal,75.36,GBP", then a total record for each currency e.g. "total,75.36,GBP".
+With -o none, it writes only the number of transactions.
+By default, the statement's order is detected from the first and last dates,
+but it can be set by flag -order instead.
+If the statement is in neither order, for example because it concatenates several statements,
+use flag -sort to sort the transactions by date.
+With flag -assert-balance, the Ledger journal entry for the last transaction of each account
+asserts the account's balance from the input format's balance field e.g. "Assets:Current  -5 GBP = 42.42 GBP",
+so that Ledger verifies the statement was imported completely.
+Filtering transactions by their codes makes the assertion fail.
+With flag -declare-accounts, Ledger journal entries are preceded by an account directive
+for each of their accounts e.g. "account Assets:Current", as required by Ledger's strict checking.
+With flag -split, the transactions of each account, such as the sub-accounts in one export file,
+are written to their own file named by substituting the account for the verb in the flag's file name template,
+rather than to standard output.
+Existing files are overwritten.
+Dates are written in layout YYYY-MM-DD, unless flag -odate gives another layout for a downstream program,
+but mcsv records are then no longer read by this module's programs.
+With flag -explicit-plus, positive amounts are written with a plus sign e.g. "+162",
+so that every amount has its sign in the same place when comparing exports.
+With flag -decimal-places, amounts are written in a fixed number of decimal places e.g. "162.50",
+rounded half to even by default or, with flag -rounding, half away from zero as some accountants require.
+With flag -amount-column, the amounts in Ledger journal entries end at that column e.g. 48,
+so that they are right-aligned as by "ledger print" rather than following their accounts by two spaces.
+With flag -against, only transactions not already in the named Ledger journal are written,
+so that a statement overlapping an earlier one can be imported into a growing journal.
+A transaction is already in the journal if an entry there has the same date, this account, amount and currency,
+and each entry, including one marked as a mirror, accounts for at most one transaction.
+The journal's dates are in the output date layout, and entries that cannot be parsed are warned about then ignored.
+
+Usage:
+
+	csv2trn [flags] [statement[:account] ...]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
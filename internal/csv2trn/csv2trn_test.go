@@ -0,0 +1,159 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package csv2trn
+
+import (
+	"errors"
+	aft "github.com/arnhemcr/financial/transaction"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyRulesCurrency(t *testing.T) {
+	rules := aft.Rules{Currencies: []aft.CurrencyRule{{ThisAccount: "Assets:Euro", Currency: "EUR"}}}
+
+	tests := []struct {
+		flagCurrency string
+		thisAccount  string
+		currency     string
+		want         string
+	}{
+		{"", "Assets:Euro", "", "EUR"},
+		{"GBP", "Assets:Euro", "", "EUR"},
+		{"GBP", "Assets:Current", "", "GBP"},
+		{"", "Assets:Current", "", ""},
+		{"GBP", "Assets:Euro", "USD", "USD"},
+	}
+
+	for _, tt := range tests {
+		ts := []aft.Transaction{{ThisAccount: tt.thisAccount, Currency: tt.currency}}
+
+		applyRules(ts, rules, config{currency: tt.flagCurrency})
+
+		if ts[0].Currency != tt.want {
+			t.Errorf("applyRules() with -c %q to %q with currency %q sets currency %q, want %q",
+				tt.flagCurrency, tt.thisAccount, tt.currency, ts[0].Currency, tt.want)
+		}
+	}
+}
+
+func TestSourceTag(t *testing.T) {
+	tests := []struct {
+		source string
+		want   map[string]string
+	}{
+		{"", nil},
+		{"NB_2025_05.csv", map[string]string{"source": "NB_2025_05.csv"}},
+	}
+
+	for _, tt := range tests {
+		ts := []aft.Transaction{{ThisAccount: "Assets:Current"}}
+
+		applyRules(ts, aft.Rules{}, config{source: tt.source})
+
+		if got := ts[0].Tags; !maps.Equal(got, tt.want) {
+			t.Errorf("tags with -source %q = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestNFieldsWarning(t *testing.T) {
+	errAmount := errors.New("amount syntax")
+	errs := []error{
+		aft.LineError{Line: 1, Err: errAmount},
+		aft.LineError{Line: 2, Err: aft.NFieldsError{NFields: 7, Found: 5}},
+		aft.LineError{Line: 3, Err: errAmount},
+		aft.LineError{Line: 4, Err: aft.NFieldsError{NFields: 7, Found: 6}},
+	}
+
+	w, others := nFieldsWarning(errs)
+
+	want := "input format expects 7 fields but line 2 has 5; skipped 2 lines with the wrong number"
+	if w != want {
+		t.Errorf("nFieldsWarning() warning = %q, want %q", w, want)
+	}
+
+	if len(others) != 2 || !errors.Is(others[0], errAmount) || !errors.Is(others[1], errAmount) {
+		t.Errorf("nFieldsWarning() other errors = %v, want those for lines 1 and 3", others)
+	}
+
+	w, others = nFieldsWarning(errs[:3])
+	if w != "" || len(others) != 3 {
+		t.Errorf("nFieldsWarning() of one wrong number of fields = %q, %v errors; want no warning, 3 errors",
+			w, len(others))
+	}
+}
+
+func TestAgainstJournalWithMirrorEntry(t *testing.T) {
+	const journal = "2025-05-04 Transfer to savings\n" +
+		"    Assets:Current  -100 GBP\n" +
+		"    Assets:Savings\n" +
+		"\n" +
+		"# mirror entry\n" +
+		"2025-05-04 Transfer from current\n" +
+		"    Assets:Savings  100 GBP\n" +
+		"    Assets:Current\n" +
+		"# end mirror entry\n"
+
+	fn := filepath.Join(t.TempDir(), "existing.journal")
+	if err := os.WriteFile(fn, []byte(journal), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	existing := loadJournal(fn, time.DateOnly)
+	if len(existing) != 2 {
+		t.Fatalf("loadJournal() returned %v transactions, want 2 including the mirror entry", len(existing))
+	}
+
+	ts := []aft.Transaction{
+		{Date: "2025-05-04", ThisAccount: "Assets:Savings", OtherAccount: "Assets:Current", Amount: 100, Currency: "GBP"},
+		{Date: "2025-05-05", ThisAccount: "Assets:Savings", OtherAccount: "Income:Interest", Amount: 1, Currency: "GBP"},
+	}
+
+	got := excludeExisting(ts, existing)
+	if len(got) != 1 || got[0].Date != "2025-05-05" {
+		t.Errorf("excludeExisting() = %v, want only the transaction on 2025-05-05", got)
+	}
+}
+
+func TestStringFields(t *testing.T) {
+	b := 42.42
+	tr := aft.Transaction{
+		Date: "2025-05-05", Memo: "Grocer", Amount: -16.92, Balance: &b, Currency: "GBP",
+		ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+	}
+
+	want := "{Amount:-16.92 Balance:42.42 Code: Currency:GBP Date:2025-05-05 Fee:0 FeeAccount: Memo:Grocer Note: " +
+		"OtherAccount:Expenses:Food Payee: Price: Status: ThisAccount:Assets:Current Time: Tags:map[]}"
+	if got := stringFields(tr); got != want {
+		t.Errorf("stringFields() = %q, want %q", got, want)
+	}
+
+	tr.Balance = nil
+	if got := stringFields(tr); !strings.Contains(got, "Balance:<nil> ") {
+		t.Errorf("stringFields() without balance = %q, want Balance:<nil>", got)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package diffmcsv implements this module's program diffmcsv, which is also subcommand "diff" of program fin.
+See the program's documentation for its behaviour.
+*/
+package diffmcsv
+
+import (
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"log"
+	"math"
+	"os"
+)
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("diffmcsv: ")
+	log.SetFlags(0)
+
+	parseFlags()
+
+	if flag.NArg() != 2 {
+		log.Fatal("two files must be named: old and new")
+	}
+
+	olds, news := load(flag.Arg(0)), load(flag.Arg(1))
+
+	var (
+		n        int // The number of differences.
+		newFound = make([]bool, len(news))
+	)
+
+	for _, o := range olds {
+		j := match(o, news, newFound)
+
+		switch {
+		case j < 0:
+			fmt.Print("- " + o.StringModuleCSV())
+		case !o.Equal(news[j]):
+			fmt.Print("< " + o.StringModuleCSV() + "> " + news[j].StringModuleCSV())
+		default:
+			continue
+		}
+
+		n++
+	}
+
+	for j, nt := range news {
+		if !newFound[j] {
+			fmt.Print("+ " + nt.StringModuleCSV())
+
+			n++
+		}
+	}
+
+	if n != 0 {
+		os.Exit(1)
+	}
+}
+
+/*
+ParseFlags parses this program's flags from the command line.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() {
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+}
+
+/*
+Load returns the transactions in the named file of this module's CSV records.
+If the file cannot be read or any record parsed, load writes messages to standard error
+and this program exits with a non-zero status.
+*/
+func load(fileName string) []aft.Transaction {
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	// Zero amounts are accepted, as they were allowed by the input format of the program that wrote the records.
+	mcsv := aft.NewModuleCSVRecordFormat()
+	mcsv.AllowZeroAmount = true
+
+	ts, err := aft.TranslateCSV(f, mcsv, "", "")
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range j.Unwrap() {
+			log.Printf("%v: %v", fileName, e)
+		}
+
+		os.Exit(1)
+	}
+
+	return ts
+}
+
+/*
+Match returns the index of the first transaction not yet found with the same date, amount and memo
+as the transaction, and marks it found.
+If there is no such transaction, match returns -1.
+*/
+func match(t aft.Transaction, ts []aft.Transaction, found []bool) int {
+	for j, o := range ts {
+		if !found[j] && o.Date == t.Date && o.Memo == t.Memo && math.Abs(o.Amount-t.Amount) <= aft.AmountTolerance {
+			found[j] = true
+
+			return j
+		}
+	}
+
+	return -1
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Diffmcsv compares two files of financial transactions in this module's comma-separated values (CSV) records
+e.g. the imports of one statement before and after a change to its input format,
+and reports the transactions added, removed and changed.
+
+Diffmcsv reads the old and new files named by its arguments, skipping any leading UTF-8 byte order mark (BOM).
+It parses each line as a transaction CSV record in this module's format (mcsv).
+If any line cannot be parsed, diffmcsv writes messages to standard error and exits with a non-zero status.
+
+Transactions are matched by their date, amount and memo, and each old transaction matches at most one new one.
+A matched pair whose other fields differ e.g. their other accounts is changed.
+Diffmcsv writes to standard output each removed transaction as its record prefixed by "- ",
+each changed pair as its old record prefixed by "< " followed by its new record prefixed by "> ",
+in the order of the old file, then each added transaction prefixed by "+ ", in the order of the new file.
+If there are any differences, diffmcsv exits with a non-zero status.
+
+Usage:
+
+	diffmcsv [flags] old new
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
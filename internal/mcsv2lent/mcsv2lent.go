@@ -0,0 +1,174 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package mcsv2lent implements this module's program mcsv2lent, which is also subcommand "journal" of program fin.
+See the program's documentation for its behaviour.
+*/
+package mcsv2lent
+
+import (
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// The configuration returned by parseFlags.
+type config struct {
+	crlf                    bool
+	currency                string
+	journalAccountsFileName string // The name of the file listing Ledger accounts with journals.
+	outDateLayout           string
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("mcsv2lent: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+
+	if !aft.IsLedgerCurrency(cfg.currency) {
+		log.Fatalf("%v: not a Ledger currency", cfg.currency)
+	}
+
+	if !aft.IsDateLayout(cfg.outDateLayout) {
+		log.Fatalf("output date layout must be Go-style e.g. %q", time.DateOnly)
+	}
+
+	aft.OutputDateLayout = cfg.outDateLayout
+
+	var (
+		err error
+		jas []string // The list of Ledger accounts with journals.
+	)
+
+	if cfg.journalAccountsFileName != "" {
+		jas, err = aft.LoadLedgerAccountNames(cfg.journalAccountsFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Zero amounts are accepted, as they were allowed by the input format of the program that wrote the records.
+	mcsv := aft.NewModuleCSVRecordFormat()
+	mcsv.AllowZeroAmount = true
+
+	ts, err := aft.TranslateCSV(os.Stdin, mcsv, "", cfg.currency)
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range j.Unwrap() {
+			log.Print(e)
+		}
+
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.crlf {
+		w = aft.NewCRLFWriter(w)
+	}
+
+	for _, t := range ts {
+		ent := t.StringLedger()
+
+		if t.IsMarkedMirror(jas) {
+			ent = aft.StartMirrorEntry + ent + aft.EndMirrorEntry
+		}
+
+		fmt.Fprint(w, ent)
+	}
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.BoolVar(&cfg.crlf, "crlf", false, "end output lines with carriage return and line feed e.g. for Windows")
+	flag.StringVar(&cfg.currency, "c", "",
+		fmt.Sprintf("Ledger currency e.g. %q or %q; used when the currency field from input is empty", "$", "GBP"))
+	flag.StringVar(&cfg.journalAccountsFileName, "f", "",
+		"name of file containing list of Ledger accounts with journals in XML")
+	flag.StringVar(&cfg.outDateLayout, "odate", time.DateOnly,
+		"Go date layout of Ledger journal entries e.g. \"01/02/2006\"")
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+MCSV2lent filters financial transactions
+from this module's comma-separated values (CSV) records to Ledger journal entries.
+If given a list of Ledger account names with journals,
+it also marks the credit entry of transfers between those accounts.
+Marked entries are discarded when those journals are merged by this module's program mrglent.
+
+MCSV2lent reads lines from standard input, skipping any leading UTF-8 byte order mark (BOM).
+It parses each line as a transaction CSV record in this module's format (mcsv).
+If any line cannot be parsed, mcsv2lent writes messages to standard error and exits with a non-zero status
+before writing any entries.
+
+The list of Ledger account names with journals is empty by default, but it can be loaded from an XML file.
+For example:
+
+    <LedgerAccountsWithJournals>
+        <Account>Assets:Current</Account>    <!-- NB.journal -->
+        <Account>Assets:Emergency</Account>  <!-- LCU.journal -->
+    </LedgerAccountsWithJournals>
+
+A transaction whose amount is positive and whose this and other accounts are both on the list is marked.
+
+MCSV2lent writes transactions to standard output in Ledger journal entry format.
+The entry for a marked transaction
+is preceded by Ledger global comment line "# mirror entry" and followed by "# end mirror entry".
+
+Usage:
+
+	mcsv2lent [flags]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,285 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package mrglent implements this module's program mrglent, which is also subcommand "merge" of program fin.
+See the program's documentation for its behaviour.
+*/
+package mrglent
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The configuration returned by parseFlags.
+type config struct {
+	autoMirror  bool
+	check       bool
+	dateLayout  string
+	keepMirrors bool
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("mrglent: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+	if !aft.IsDateLayout(cfg.dateLayout) {
+		log.Fatalf("date layout must be Go-style e.g. %q", time.DateOnly)
+	}
+
+	parse := aft.ParseLedgerEntries
+	if cfg.keepMirrors {
+		parse = aft.ParseAllLedgerEntries
+	}
+
+	es, err := parse(os.Stdin, cfg.dateLayout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sortEntries(es)
+
+	if cfg.autoMirror {
+		es = discardMirrors(es, cfg.dateLayout, cfg.keepMirrors)
+	}
+
+	if cfg.check {
+		ps := findMirrors(es, cfg.dateLayout)
+		for _, p := range ps {
+			log.Printf("possible unmarked mirror entries: %q and %q", firstLine(es[p[0]]), firstLine(es[p[1]]))
+		}
+
+		if len(ps) != 0 {
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	writeEntries(os.Stdout, es)
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.BoolVar(&cfg.autoMirror, "auto-mirror", false,
+		"discard the credit entry of each pair of unmarked entries that look like both sides of one transfer")
+	flag.BoolVar(&cfg.check, "check", false,
+		"instead of writing entries, report possible unmarked mirror entries and exit with a non-zero status if any")
+	flag.StringVar(&cfg.dateLayout, "d", time.DateOnly, "Go date layout of Ledger journal entries")
+	flag.BoolVar(&cfg.keepMirrors, "keep-mirrors", false,
+		"instead of discarding mirror entries, write them marked and with Ledger tag \"mirror\"")
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+/*
+DiscardMirrors returns the Ledger journal entries, ordered by date, without the credit entry of each pair
+found by findMirrors.
+As for entries marked by mcsv2lent, the debit entry is kept.
+If keep is set, the credit entries are instead kept as mirrors.
+*/
+func discardMirrors(es []aft.LedgerEntry, dateLayout string, keep bool) []aft.LedgerEntry {
+	discard := make([]bool, len(es))
+	for _, p := range findMirrors(es, dateLayout) {
+		if keep {
+			es[p[1]].Mirror = true
+		} else {
+			discard[p[1]] = true
+		}
+	}
+
+	var kept []aft.LedgerEntry
+
+	for i, e := range es {
+		if !discard[i] {
+			kept = append(kept, e)
+		}
+	}
+
+	return kept
+}
+
+/*
+FindMirrors returns the indexes of pairs of Ledger journal entries, ordered by date, that could be mirrors:
+the two sides of one transfer between accounts (see [aft.Transaction.IsMirror]).
+The debit entry, whose amount is negative, is first in each pair and the credit entry second.
+Each entry is in at most one pair, and entries already marked as mirrors are not paired.
+An entry that cannot be parsed as a transaction is not paired, and a message is written to standard error.
+*/
+func findMirrors(es []aft.LedgerEntry, dateLayout string) [][2]int {
+	var (
+		ps     [][2]int
+		paired = make([]bool, len(es))
+		ts     = make([]aft.Transaction, len(es))
+	)
+
+	for i, e := range es {
+		if e.Mirror {
+			paired[i] = true
+
+			continue
+		}
+
+		err := ts[i].ParseLedger(e.Text, dateLayout)
+		if err != nil {
+			log.Printf("cannot check entry %q: %v", firstLine(e), err)
+
+			paired[i] = true
+		}
+	}
+
+	for i := range es {
+		for j := i + 1; j < len(es) && es[j].Date == es[i].Date && !paired[i]; j++ {
+			if !paired[j] && ts[i].IsMirror(ts[j]) {
+				p := [2]int{i, j}
+				if 0 < ts[i].Amount {
+					p = [2]int{j, i}
+				}
+
+				ps = append(ps, p)
+				paired[i], paired[j] = true, true
+			}
+		}
+	}
+
+	return ps
+}
+
+// FirstLine returns the first line of the Ledger journal entry, which has its date and memo.
+func firstLine(e aft.LedgerEntry) string {
+	ln, _, _ := strings.Cut(e.Text, "\n")
+
+	return ln
+}
+
+/*
+TagMirror returns the text of the Ledger journal entry tagged "mirror" and between mirror entry comment lines,
+so that reports can exclude it e.g. "ledger balance not %mirror" and mrglent discards it by default.
+*/
+func tagMirror(e aft.LedgerEntry) string {
+	const tag = "    ; :mirror:\n"
+
+	first, rest, _ := strings.Cut(e.Text, "\n")
+	if !strings.HasPrefix(rest, tag) {
+		// The entry was not tagged by an earlier merge.
+		rest = tag + rest
+	}
+
+	return aft.StartMirrorEntry + first + "\n" + rest + aft.EndMirrorEntry
+}
+
+// WriteEntries writes the text of the Ledger journal entries, tagging those that are mirrors, to the writer.
+func writeEntries(w io.Writer, es []aft.LedgerEntry) {
+	for _, e := range es {
+		if e.Mirror {
+			fmt.Fprint(w, tagMirror(e))
+		} else {
+			fmt.Fprint(w, e.Text)
+		}
+	}
+}
+
+/*
+SortEntries orders a list of Ledger journal entries by date then time ascending, where no time comes first.
+The sort is stable, so entries with the same date and time keep their order.
+*/
+func sortEntries(es []aft.LedgerEntry) {
+	slices.SortStableFunc(es, func(a, b aft.LedgerEntry) int {
+		return cmp.Or(strings.Compare(a.Date, b.Date), strings.Compare(a.Time, b.Time))
+	})
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Mrglent filters financial transactions in Ledger entry format from multiple journals into a general journal.
+
+Mrglent reads Ledger journals from standard input.
+It extracts dated journal entries.
+If an entry's date cannot be parsed according to the layout, 
+mrglent writes a message to standard error and exits with a non-zero status.
+Dated entries marked as mirrors (between "# mirror entry" and "# end mirror entry" comment lines) are discarded.
+See this module's program mcsv2lent for more on marked entries.
+All other journal content is also discarded including automatic transactions, global comments and command directives.
+
+Mrglent orders the entries by date ascending and writes them to standard output.
+Entries on the same date are ordered by their optional time of day after the date e.g. "2025-05-05 09:30",
+with entries without a time first, and otherwise keep the order in which they were read.
+
+With flag -auto-mirror, mrglent also discards unmarked mirror entries.
+For each pair of entries that look like both sides of one transfer, as described below,
+the credit entry is discarded and the debit entry kept, as if the credit entry had been marked by mcsv2lent.
+This heuristic cannot tell a mirror entry from a genuine entry that happens to match another,
+which it would discard, so marking mirror entries with mcsv2lent remains the default.
+Transfers that take more than a day to arrive are not found, so those must still be marked.
+
+With flag -check, mrglent instead reports possible unmarked mirror entries to standard error
+and exits with a non-zero status if there are any.
+A missing mirror marker leaves a transfer between accounts with journals counted twice in the general journal.
+Two entries are reported if they have the same date, currency and equal and opposite amounts,
+and the accounts of one are those of the other swapped.
+
+With flag -keep-mirrors, mrglent keeps mirror entries, for an audit trail, rather than discarding them.
+Each is written between mirror entry comment lines and tagged "mirror" with comment "; :mirror:",
+so reports can exclude it e.g. "ledger balance not %mirror".
+With flag -auto-mirror as well, the credit entries it finds are also kept in this way.
+
+Usage:
+
+	mrglent [flags]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package mrglent
+
+import (
+	"bytes"
+	aft "github.com/arnhemcr/financial/transaction"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestMergeOrder checks the exact output of merging the journals in testdata against golden files.
+func TestMergeOrder(t *testing.T) {
+	tests := []struct {
+		keepMirrors bool
+		golden      string
+	}{
+		{false, "merged.golden"},
+		{true, "merged-keep-mirrors.golden"},
+	}
+
+	for _, tt := range tests {
+		in, err := os.Open(filepath.Join("testdata", "journals.ledger"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parse := aft.ParseLedgerEntries
+		if tt.keepMirrors {
+			parse = aft.ParseAllLedgerEntries
+		}
+
+		es, err := parse(in, time.DateOnly)
+		in.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sortEntries(es)
+
+		var got bytes.Buffer
+
+		writeEntries(&got, es)
+
+		want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("merge with keepMirrors %v:\ngot:\n%s\nwant:\n%s", tt.keepMirrors, got.Bytes(), want)
+		}
+	}
+}
+
+func TestSortEntriesStable(t *testing.T) {
+	es := []aft.LedgerEntry{
+		{Date: "2025-05-06", Text: "a"},
+		{Date: "2025-05-05", Text: "b"},
+		{Date: "2025-05-05", Time: "09:30", Text: "c"},
+		{Date: "2025-05-05", Text: "d"},
+		{Date: "2025-05-04", Text: "e"},
+		{Date: "2025-05-05", Text: "f"},
+	}
+
+	sortEntries(es)
+
+	var got []string
+	for _, e := range es {
+		got = append(got, e.Text)
+	}
+
+	if want := []string{"e", "b", "d", "f", "c", "a"}; !slices.Equal(got, want) {
+		t.Errorf("sortEntries() order = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package mrgmcsv implements this module's program mrgmcsv, which is also subcommand "merge-mcsv" of program fin.
+See the program's documentation for its behaviour.
+*/
+package mrgmcsv
+
+import (
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"io"
+	"log"
+	"os"
+)
+
+// The configuration returned by parseFlags.
+type config struct {
+	autoMirror              bool
+	crlf                    bool
+	journalAccountsFileName string // The name of the file listing Ledger accounts with journals.
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("mrgmcsv: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+
+	var (
+		err error
+		jas []string // The list of Ledger accounts with journals.
+	)
+
+	if cfg.journalAccountsFileName != "" {
+		jas, err = aft.LoadLedgerAccountNames(cfg.journalAccountsFileName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Zero amounts are accepted, as they were allowed by the input format of the program that wrote the records.
+	mcsv := aft.NewModuleCSVRecordFormat()
+	mcsv.AllowZeroAmount = true
+
+	ts, err := aft.TranslateCSV(os.Stdin, mcsv, "", "")
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range j.Unwrap() {
+			log.Print(e)
+		}
+
+		os.Exit(1)
+	}
+
+	var kept []aft.Transaction
+
+	for _, t := range ts {
+		if !t.IsMarkedMirror(jas) {
+			kept = append(kept, t)
+		}
+	}
+
+	aft.SortTransactions(kept)
+
+	if cfg.autoMirror {
+		kept = discardMirrors(kept)
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.crlf {
+		w = aft.NewCRLFWriter(w)
+	}
+
+	err = aft.WriteTransactions(w, kept, aft.ModuleCSV)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.BoolVar(&cfg.autoMirror, "auto-mirror", false,
+		"discard the credit transaction of each pair of transactions that look like both sides of one transfer")
+	flag.BoolVar(&cfg.crlf, "crlf", false, "end output lines with carriage return and line feed e.g. for Windows")
+	flag.StringVar(&cfg.journalAccountsFileName, "f", "",
+		"name of file containing list of Ledger accounts with journals in XML")
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+/*
+DiscardMirrors returns the transactions, ordered by date, without the credit transaction of each pair
+that could be the two sides of one transfer (see [aft.Transaction.IsMirror]).
+As for mrglent, the debit transaction is kept and each transaction is in at most one pair.
+*/
+func discardMirrors(ts []aft.Transaction) []aft.Transaction {
+	discard := make([]bool, len(ts))
+	paired := make([]bool, len(ts))
+
+	for i := range ts {
+		for j := i + 1; j < len(ts) && ts[j].Date == ts[i].Date && !paired[i]; j++ {
+			if !paired[j] && ts[i].IsMirror(ts[j]) {
+				if 0 < ts[i].Amount {
+					discard[i] = true
+				} else {
+					discard[j] = true
+				}
+
+				paired[i], paired[j] = true, true
+			}
+		}
+	}
+
+	var kept []aft.Transaction
+
+	for i, t := range ts {
+		if !discard[i] {
+			kept = append(kept, t)
+		}
+	}
+
+	return kept
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Mrgmcsv merges financial transactions in this module's comma-separated values (CSV) records
+from multiple files into one, without converting them to Ledger journals first.
+
+Mrgmcsv reads lines from standard input, skipping any leading UTF-8 byte order mark (BOM).
+It parses each line as a transaction CSV record in this module's format (mcsv).
+If any line cannot be parsed, mrgmcsv writes messages to standard error and exits with a non-zero status
+before writing any records.
+
+If given a list of Ledger account names with journals, as for mcsv2lent,
+mrgmcsv discards the credit transaction of transfers between those accounts,
+which mcsv2lent would mark as mirror entries and mrglent discard.
+A transaction whose amount is positive and whose this and other accounts are both on the list is discarded.
+
+Mrgmcsv orders the transactions by date ascending and writes them to standard output in mcsv.
+Transactions on the same date keep the order in which they were read.
+
+With flag -auto-mirror, mrgmcsv also discards the credit transaction of each pair
+that looks like both sides of one transfer, as does mrglent with the same flag.
+
+Usage:
+
+	mrgmcsv [flags]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package normlent implements this module's program normlent, which is also subcommand "normalise" of program fin.
+See the program's documentation for its behaviour.
+*/
+package normlent
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"log"
+	"os"
+	"time"
+)
+
+// The configuration returned by parseFlags.
+type config struct {
+	dateLayouts []string
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("normlent: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+
+	for _, dl := range cfg.dateLayouts {
+		if !aft.IsDateLayout(dl) {
+			log.Fatalf("%v: date layout must be Go-style e.g. %q", dl, time.DateOnly)
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+
+	err := aft.NormaliseLedgerDates(os.Stdin, w, cfg.dateLayouts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.Func("d", fmt.Sprintf("Go date layout of Ledger journal entries e.g. %q; may be repeated, "+
+		"and layouts are tried in order (default %q and %q)", "01-02-2006", time.DateOnly, "2006/01/02"),
+		func(s string) error {
+			cfg.dateLayouts = append(cfg.dateLayouts, s)
+
+			return nil
+		})
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	if len(cfg.dateLayouts) == 0 {
+		cfg.dateLayouts = []string{time.DateOnly, "2006/01/02"}
+	}
+
+	return cfg
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Normlent filters a Ledger journal, normalising the dates of its financial transactions.
+
+Normlent reads a Ledger journal from standard input.
+It rewrites the date of each dated journal entry, and any auxiliary date, in this module's layout YYYY-MM-DD.
+Each date is parsed according to the first of the date layouts that succeeds,
+so a journal whose entries mix layouts from different eras can be normalised before it is merged by mrglent.
+If a date cannot be parsed, normlent writes a message to standard error and exits with a non-zero status.
+All other journal content, including the rest of each entry, is written unchanged to standard output.
+
+Usage:
+
+	normlent [flags]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Package splitlent implements this module's program splitlent, which is also subcommand "split" of program fin.
+See the program's documentation for its behaviour.
+*/
+package splitlent
+
+import (
+	"flag"
+	"fmt"
+	aft "github.com/arnhemcr/financial/transaction"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// The configuration returned by parseFlags.
+type config struct {
+	dateLayout       string
+	fileNameTemplate string
+}
+
+/*
+Main runs this program, which parses its flags from the command line and exits with a non-zero status if it fails.
+*/
+func Main() {
+	log.SetPrefix("splitlent: ")
+	log.SetFlags(0)
+
+	cfg := parseFlags()
+	if !aft.IsDateLayout(cfg.dateLayout) {
+		log.Fatalf("date layout must be Go-style e.g. %q", time.DateOnly)
+	}
+
+	if strings.Contains(fmt.Sprintf(cfg.fileNameTemplate, ""), "%!") {
+		log.Fatalf("%v: file name template must contain one verb e.g. %q", cfg.fileNameTemplate, "%v.journal")
+	}
+
+	es, err := aft.ParseLedgerEntries(os.Stdin, cfg.dateLayout)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	as, a2txt := splitEntries(es)
+	for _, a := range as {
+		fn := fmt.Sprintf(cfg.fileNameTemplate, a)
+
+		err = os.WriteFile(fn, []byte(a2txt[a]), 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+/*
+ParseFlags returns this program's configuration parsed from command line flags.
+If help was requested, parseFlags writes this program's help text then exits.
+If the flags are invalid, this program exits with a non-zero status.
+*/
+func parseFlags() config {
+	var cfg config
+
+	flag.StringVar(&cfg.dateLayout, "d", time.DateOnly, "Go date layout of Ledger journal entries")
+	flag.StringVar(&cfg.fileNameTemplate, "n", "%v.journal",
+		fmt.Sprintf("file name template for journals; %q is replaced by this account", "%v"))
+
+	var help bool
+
+	flag.BoolVar(&help, "h", false, "write this help text then exit")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if help {
+		usage()
+		os.Exit(0)
+	}
+
+	return cfg
+}
+
+/*
+SplitEntries groups the texts of a list of Ledger journal entries by this account.
+It returns the accounts in the order they were first found and the concatenated texts for each account.
+*/
+func splitEntries(es []aft.LedgerEntry) ([]string, map[string]string) {
+	a2txt := make(map[string]string)
+
+	var as []string
+
+	for _, e := range es {
+		a := e.Account()
+		if a == "" {
+			log.Printf("entry has no postings: %q", strings.TrimSuffix(e.Text, "\n"))
+
+			continue
+		}
+
+		_, found := a2txt[a]
+		if !found {
+			as = append(as, a)
+		}
+
+		a2txt[a] += e.Text
+	}
+
+	return as, a2txt
+}
+
+// Usage writes the help text for this program.
+func usage() {
+	fmt.Fprint(os.Stderr, `
+Splitlent splits financial transactions in Ledger entry format from a journal into one journal per account.
+It is the inverse of this module's program mrglent.
+
+Splitlent reads a Ledger journal from standard input.
+It extracts dated journal entries in the same way as mrglent.
+If an entry's date cannot be parsed according to the layout,
+splitlent writes a message to standard error and exits with a non-zero status.
+All other journal content is discarded.
+
+Each entry belongs to the account of its first posting, which is called this account.
+Splitlent writes the entries for each account, in the order they were read,
+to a file named by substituting the account for the verb in the file name template.
+Existing files are overwritten.
+An entry without postings is discarded with a message to standard error.
+
+Usage:
+
+	splitlent [flags]
+
+The flags are:
+
+`)
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Mrgmcsv [filters] financial transactions in this module's [comma-separated values (CSV)] records
+from multiple files into one, without converting them to [Ledger] journals first.
+
+Mrgmcsv reads lines from standard input, skipping any leading UTF-8 byte order mark (BOM).
+It parses each line as a transaction CSV record in this module's format (mcsv).
+If any line cannot be parsed, mrgmcsv writes messages to standard error and exits with a non-zero status
+before writing any records.
+
+If given a list of Ledger account names with journals, as for mcsv2lent,
+mrgmcsv discards the credit transaction of transfers between those accounts,
+which mcsv2lent would mark as mirror entries and mrglent discard.
+A transaction whose amount is positive and whose this and other accounts are both on the list is discarded.
+
+Mrgmcsv orders the transactions by date ascending and writes them to standard output in mcsv.
+Transactions on the same date keep the order in which they were read.
+
+With flag -auto-mirror, mrgmcsv also discards the credit transaction of each pair
+that looks like both sides of one transfer, as does mrglent with the same flag.
+
+Usage:
+
+	mrgmcsv [flags]
+
+The flags are:
+
+	-auto-mirror
+	  	discard the credit transaction of each pair of transactions that look like both sides of one transfer
+	-crlf
+	  	end output lines with carriage return and line feed e.g. for Windows
+	-f string
+	  	name of file containing list of Ledger accounts with journals in XML
+	-h	write this help text then exit
+
+See also [this package's README].
+
+[comma-separated values (CSV)]: https://en.wikipedia.org/wiki/Comma-separated_values
+[filters]: https://en.wikipedia.org/wiki/Filter_(software)
+[Ledger]: https://ledger-cli.org
+[this package's README]: https://github.com/arnhemcr/financial/tree/main
+*/
+package main
+
+import "github.com/arnhemcr/financial/internal/mrgmcsv"
+
+func main() {
+	mrgmcsv.Main()
+}
@@ -0,0 +1,54 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Normlent [filters] a [Ledger] journal, normalising the dates of its financial transactions.
+
+Normlent reads a Ledger journal from standard input.
+It rewrites the date of each dated journal entry, and any auxiliary date, in this module's layout YYYY-MM-DD.
+Each date is parsed according to the first of the date layouts that succeeds,
+so a journal whose entries mix layouts from different eras can be normalised before it is merged by mrglent.
+If a date cannot be parsed, normlent writes a message to standard error and exits with a non-zero status.
+All other journal content, including the rest of each entry, is written unchanged to standard output.
+
+Usage:
+
+	normlent [flags]
+
+The flags are:
+
+	-d value
+	  	Go date layout of Ledger journal entries e.g. "01-02-2006"; may be repeated, and layouts are tried in order (default "2006-01-02" and "2006/01/02")
+	-h	write this help text then exit
+
+See also [this package's README].
+
+[filters]: https://en.wikipedia.org/wiki/Filter_(software)
+[Ledger]: https://ledger-cli.org
+[this package's README]: https://github.com/arnhemcr/financial/tree/main
+*/
+package main
+
+import "github.com/arnhemcr/financial/internal/normlent"
+
+func main() {
+	normlent.Main()
+}
@@ -0,0 +1,61 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+/*
+Splitlent splits financial transactions in [Ledger] entry format from a journal into one journal per account.
+It is the inverse of this module's program mrglent.
+
+Splitlent reads a Ledger journal from standard input.
+It extracts dated journal entries in the same way as mrglent.
+If an entry's date cannot be parsed according to the layout,
+splitlent writes a message to standard error and exits with a non-zero status.
+All other journal content is discarded.
+
+Each entry belongs to the account of its first posting, which is called this account.
+Splitlent writes the entries for each account, in the order they were read,
+to a file named by substituting the account for the verb in the file name template.
+Existing files are overwritten.
+An entry without postings is discarded with a message to standard error.
+
+Usage:
+
+	splitlent [flags]
+
+The flags are:
+
+	-d string
+	  	Go date layout of Ledger journal entries (default "2006-01-02")
+	-h	write this help text then exit
+	-n string
+	  	file name template for journals; "%v" is replaced by this account (default "%v.journal")
+
+See also [this package's README].
+
+[Ledger]: https://ledger-cli.org
+[this package's README]: https://github.com/arnhemcr/financial/tree/main
+*/
+package main
+
+import "github.com/arnhemcr/financial/internal/splitlent"
+
+func main() {
+	splitlent.Main()
+}
@@ -22,9 +22,12 @@ If not, see <https://www.gnu.org/licenses/>.
 package transaction
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -32,29 +35,64 @@ var (
 	errAmountSyntax   = errors.New("parseDecimal: string must be integer or decimal")
 	errAmountZero     = errors.New("parseAmount: amount cannot be zero")
 	errCreditDebit    = errors.New("parseAmount: credit and debit cannot both be empty string or both non-empty string")
+	errDecimalPlaces  = errors.New("checkDecimalPlaces: amount has more decimal places than the format allows")
 	errPositiveNumber = errors.New("parsePositiveDecimal: number must be positive")
+	errSign           = errors.New("parseAmount: sign must be \"CR\", \"C\", \"DR\" or \"D\"")
 )
 
 /*
 ParseAmount parses the value of a transaction from either the amount, credit or debit fields.
-The value cannot be zero.
-If it fails to parse a non-zero value, parseAmount returns the first error.
+The value cannot be zero, unless the format allows zero amounts, in which case negative zero becomes zero.
+If the format has minor unit digits, the value is converted from minor units e.g. cents.
+If the format has a sign field, the amount must be positive and is negative if the sign is a debit e.g. "DR".
+A debit is negative whether or not its field has a minus sign, if the format allows signed debits.
+If instead the format has signed credits and debits, a negative credit is negative and a negative debit positive.
+If it fails to parse a non-zero value, parseAmount returns the first error,
+which is a [FieldError] if a field has a bad value.
 */
 func parseAmount(fields []string, crf CSVRecordFormat) (float64, error) {
-	a, c, d := fields[crf.AmountI], fields[crf.CreditI], fields[crf.DebitI]
+	a, c, d := crf.amountField(fields, crf.AmountI), crf.amountField(fields, crf.CreditI),
+		crf.amountField(fields, crf.DebitI)
 
 	var (
-		v   float64
-		err error
+		v    float64
+		err  error
+		name = "amount" // The name of the field parsed.
+		i    = crf.AmountI
 	)
 
 	switch {
+	case a != "" && crf.SignI != 0:
+		v, err = parsePositiveDecimal(a, crf.AllowZeroAmount)
+
+		switch strings.ToUpper(strings.TrimSpace(field(fields, crf.SignI))) {
+		case "CR", "C":
+		case "DR", "D":
+			v *= -1
+		default:
+			return 0, newFieldError("sign", crf.SignI, fields, errSign)
+		}
 	case a != "":
 		v, err = parseDecimal(a)
+	case c != "" && d == "" && crf.SignedCreditDebit:
+		name, i = "credit", crf.CreditI
+		v, err = parseDecimal(c)
 	case c != "" && d == "":
-		v, err = parsePositiveDecimal(c)
+		name, i = "credit", crf.CreditI
+		v, err = parsePositiveDecimal(c, crf.AllowZeroAmount)
+	case d != "" && c == "" && crf.SignedCreditDebit:
+		name, i = "debit", crf.DebitI
+		v, err = parseDecimal(d)
+
+		v *= -1
+	case d != "" && c == "" && crf.SignedDebit:
+		name, i = "debit", crf.DebitI
+		v, err = parseDecimal(d)
+
+		v = -math.Abs(v)
 	case d != "" && c == "":
-		v, err = parsePositiveDecimal(d)
+		name, i = "debit", crf.DebitI
+		v, err = parsePositiveDecimal(d, crf.AllowZeroAmount)
 
 		v *= -1
 	default:
@@ -63,23 +101,160 @@ func parseAmount(fields []string, crf CSVRecordFormat) (float64, error) {
 
 	switch {
 	case err != nil:
-		return 0, err
+		return 0, newFieldError(name, i, fields, err)
+	case v == 0 && !crf.AllowZeroAmount:
+		return 0, newFieldError(name, i, fields, errAmountZero)
 	case v == 0:
-		return 0, errAmountZero
+		return 0, nil // Negative zero would otherwise be written as "-0".
 	default:
-		return v, nil
+		return crf.fromMinorUnits(v), nil
+	}
+}
+
+/*
+FromMinorUnits returns the number in minor units e.g. cents converted to major units,
+if this CSV record format has minor unit digits.
+If not, fromMinorUnits returns the number unchanged.
+*/
+func (crf CSVRecordFormat) fromMinorUnits(n float64) float64 {
+	if crf.MinorUnitDigits == 0 {
+		return n
+	}
+
+	return roundAmount(n / math.Pow10(int(crf.MinorUnitDigits)))
+}
+
+/*
+CheckDecimalPlaces returns an error if the amount, credit or debit field has more decimal places
+than this CSV record format allows e.g. "16.925" when two places are allowed.
+If the format does not limit decimal places, checkDecimalPlaces returns nil.
+*/
+func (crf CSVRecordFormat) checkDecimalPlaces(fields []string) error {
+	if crf.MaxDecimalPlaces == 0 {
+		return nil
+	}
+
+	names := [...]string{"amount", "credit", "debit"}
+
+	for j, i := range [...]uint8{crf.AmountI, crf.CreditI, crf.DebitI} {
+		// A sign, which may trail the number e.g. "16.92-", is not a decimal place.
+		n := strings.TrimSuffix(strings.TrimLeft(strings.TrimSpace(crf.amountField(fields, i)), "+-"), "-")
+
+		_, frac, _ := strings.Cut(n, ".")
+		if len(strings.TrimSpace(frac)) > int(crf.MaxDecimalPlaces) {
+			return newFieldError(names[j], i, fields, errDecimalPlaces)
+		}
+	}
+
+	return nil
+}
+
+/*
+AmountField returns the number in the field with the index in the CSV record fields,
+without any currency if this CSV record format allows currencies in amounts, ready for parseDecimal.
+*/
+func (crf CSVRecordFormat) amountField(fields []string, i uint8) string {
+	s := field(fields, i)
+	if crf.CurrencyInAmount {
+		s, _ = cutCurrency(s)
+	}
+
+	return crf.normaliseDecimal(s)
+}
+
+/*
+AmountCurrency returns the currency in the first of the amount, credit and debit fields that has one,
+if this CSV record format allows currencies in amounts.
+If not, amountCurrency returns empty string.
+*/
+func (crf CSVRecordFormat) amountCurrency(fields []string) string {
+	if !crf.CurrencyInAmount {
+		return ""
+	}
+
+	for _, i := range [...]uint8{crf.AmountI, crf.CreditI, crf.DebitI} {
+		_, cu := cutCurrency(field(fields, i))
+		if cu != "" {
+			return cu
+		}
+	}
+
+	return ""
+}
+
+/*
+CutCurrency returns the number in the string without a currency code and a known currency symbol, if any,
+and the currency, which is the code if there is one or else the symbol.
+For example, "NZD $-162.00" gives "-162.00" and "NZD", while "-$5" gives "-5" and "$".
+A code is three upper-case letters separated from the number by white space.
+*/
+func cutCurrency(s string) (string, string) {
+	var (
+		code string
+		rest []string
+	)
+
+	for _, f := range strings.Fields(s) {
+		if code == "" && isCurrencyCode(f) {
+			code = f
+
+			continue
+		}
+
+		rest = append(rest, f)
 	}
+
+	num := strings.Join(rest, "")
+
+	for _, sym := range CurrencySymbols {
+		if strings.Contains(num, sym) {
+			return strings.Replace(num, sym, "", 1), cmp.Or(code, sym)
+		}
+	}
+
+	return num, code
+}
+
+/*
+NormaliseDecimal returns the number string with the separators in this CSV record format
+replaced by those expected by parseDecimal.
+Thousands separators are removed and the decimal separator, which is '.' if empty, is replaced by '.'.
+If the decimal separator is not '.', any '.' is replaced by a character that parseDecimal rejects.
+*/
+func (crf CSVRecordFormat) normaliseDecimal(s string) string {
+	ds, ts := cmp.Or(crf.DecimalSeparator, "."), crf.ThousandsSeparator
+	if ds == "." && ts == "" {
+		return s
+	}
+
+	var olds []string
+
+	if ts != "" {
+		olds = append(olds, ts, "")
+	}
+
+	if ds != "." {
+		olds = append(olds, ds, ".", ".", "_")
+	}
+
+	return strings.NewReplacer(olds...).Replace(s)
 }
 
 /*
 ParseDecimal returns the floating-point number parsed from the string.
 If the string does not have the following syntax or it fails to parse a number, parseDecimal returns the error.
 
-	number = [ "-" | "+" ] ( integer_decimal | decimal )
+	number = [ "-" | "+" ] unsigned | unsigned "-"
+	unsigned = integer_decimal | decimal
 	integer_decimal = decimal_digits [ "." [ decimal_digits ] ]
 	decimal = "." decimal_digits
 */
 func parseDecimal(s string) (float64, error) {
+	// Some accounting packages write a negative number with a trailing minus e.g. "162.00-".
+	if u, ok := strings.CutSuffix(s, "-"); ok && u != "" && u[0] != '-' && u[0] != '+' {
+		s = "-" + u
+	}
+
 	var postPoint bool
 
 	for i, r := range s {
@@ -93,7 +268,8 @@ func parseDecimal(s string) (float64, error) {
 		}
 	}
 
-	n, err := strconv.ParseFloat(s, 64)
+	// A positive sign is redundant, so strip it rather than rely on ParseFloat accepting it.
+	n, err := strconv.ParseFloat(strings.TrimPrefix(s, "+"), 64)
 	if err != nil {
 		return 0, fmt.Errorf("parseDecimal: %w", err)
 	}
@@ -103,22 +279,99 @@ func parseDecimal(s string) (float64, error) {
 
 /*
 ParsePositiveDecimal returns the positive floating-point number parsed from the string.
-If it fails to parse a positive number, parsePositiveDecimal returns the first error.
+If zero is allowed, the number can also be zero.
+If it fails to parse such a number, parsePositiveDecimal returns the first error.
 */
-func parsePositiveDecimal(s string) (float64, error) {
+func parsePositiveDecimal(s string, allowZero bool) (float64, error) {
 	n, err := parseDecimal(s)
 
 	switch {
 	case err != nil:
 		return 0, err
-	case n <= 0:
+	case n < 0 || (n == 0 && !allowZero):
 		return 0, errPositiveNumber
 	default:
 		return n, nil
 	}
 }
 
-// StringAmount returns the floating-point number as a string.
+/*
+RoundAmount returns the floating-point number rounded to the nearest billionth,
+which removes the rounding errors from adding amounts while keeping any of their decimal places.
+*/
+func roundAmount(n float64) float64 {
+	return math.Round(n*1e9) / 1e9
+}
+
+/*
+ExplicitPlus is whether positive amounts are written with a plus sign e.g. "+162" rather than "162",
+as Ledger journal entries, this module's CSV records and OFX documents.
+This keeps the sign of every amount in the same place e.g. for comparing exports line by line.
+It defaults to false.
+GnuCash import records, whose deposits and withdrawals are unsigned, and JSON arrays are not affected.
+*/
+var ExplicitPlus = false
+
+// StringAmount returns the floating-point number as a string, with a plus sign if it is positive and ExplicitPlus.
 func stringAmount(n float64) string {
-	return strconv.FormatFloat(n, 'f', -1, 64)
+	s := stringUnsignedAmount(n)
+	if ExplicitPlus && 0 < n {
+		s = "+" + s
+	}
+
+	return s
+}
+
+/*
+OutputDecimalPlaces is the number of decimal places in which amounts are written e.g. 2 for "162.50",
+or -1, the default, for as many as each amount needs e.g. "162.5".
+Programs may change it and OutputRounding, which rounds amounts with more places.
+JSON arrays are not affected.
+*/
+var OutputDecimalPlaces = -1
+
+// The names of the rounding modes for amounts with more decimal places than OutputDecimalPlaces.
+const (
+	RoundHalfEven = "half-even" // Halves are rounded to the even neighbour e.g. 0.125 to 0.12, as by bankers.
+	RoundHalfUp   = "half-up"   // Halves are rounded away from zero e.g. 0.125 to 0.13 and -0.125 to -0.13.
+)
+
+// OutputRounding is the rounding mode of amounts written in OutputDecimalPlaces, which defaults to RoundHalfEven.
+var OutputRounding = RoundHalfEven
+
+// IsRounding reports whether the string is the name of a rounding mode.
+func IsRounding(s string) bool {
+	return s == RoundHalfEven || s == RoundHalfUp
+}
+
+// StringUnsignedAmount returns the floating-point number as a string in OutputDecimalPlaces without a plus sign.
+func stringUnsignedAmount(n float64) string {
+	if OutputDecimalPlaces < 0 {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	return strconv.FormatFloat(roundPlaces(n, OutputDecimalPlaces, OutputRounding), 'f', OutputDecimalPlaces, 64)
+}
+
+/*
+RoundPlaces returns the floating-point number rounded to the number of decimal places in the rounding mode.
+The number is first rounded to the nearest billionth, as by roundAmount,
+so that a decimal half such as 2.675, which is slightly less in binary, is rounded as a half.
+Negative zero becomes zero.
+*/
+func roundPlaces(n float64, places int, rounding string) float64 {
+	p := math.Pow10(places)
+	scaled := math.Round(n*p*1e9) / 1e9
+
+	if rounding == RoundHalfUp {
+		scaled = math.Round(scaled)
+	} else {
+		scaled = math.RoundToEven(scaled)
+	}
+
+	if scaled == 0 {
+		return 0
+	}
+
+	return scaled / p
 }
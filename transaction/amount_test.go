@@ -0,0 +1,188 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A format built in code, rather than loaded, leaves its decimal separator empty, which means '.'.
+func TestFormatLiteralWithoutDecimalSeparator(t *testing.T) {
+	crf := CSVRecordFormat{NFields: 3, DateI: 1, MemoI: 2, AmountI: 3, DateLayout: time.DateOnly}
+
+	err := crf.Validate()
+	if err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	ts, err := TranslateCSV(strings.NewReader("2025-01-01,Coffee,1.50\n2025-01-02,Tea,-2\n"), crf, "Assets:Current", "")
+	if err != nil {
+		t.Fatalf("TranslateCSV() error = %v", err)
+	}
+
+	want := []float64{1.5, -2}
+	if len(ts) != len(want) {
+		t.Fatalf("TranslateCSV() returned %v transactions, want %v", len(ts), len(want))
+	}
+
+	for i, w := range want {
+		if ts[i].Amount != w {
+			t.Errorf("transaction %v amount = %v, want %v", i, ts[i].Amount, w)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{"162.00", 162, false},
+		{"-98.765", -98.765, false},
+		{"+1234", 1234, false},
+		{".5", 0.5, false},
+		{"162.00-", -162, false},
+		{"5-", -5, false},
+		{"-5-", 0, true},
+		{"+5-", 0, true},
+		{"-", 0, true},
+		{"1.2.3", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDecimal(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseDecimal(%q) error = %v, want error %v", tt.s, err, tt.wantErr)
+		} else if got != tt.want {
+			t.Errorf("parseDecimal(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDecimalPlaces(t *testing.T) {
+	crf := NewModuleCSVRecordFormat()
+	crf.MaxDecimalPlaces = 2
+
+	tests := []struct {
+		amount  string
+		wantErr bool
+	}{
+		{"16.92", false},
+		{"-16.92", false},
+		{"+16.92", false},
+		{"16.92-", false},
+		{"16-", false},
+		{"16.925", true},
+		{"16.925-", true},
+	}
+
+	for _, tt := range tests {
+		fields := []string{"2025-01-01", "Assets:Current", "Imbalance", "", "Memo", tt.amount, "GBP"}
+
+		err := crf.checkDecimalPlaces(fields)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkDecimalPlaces() with amount %q error = %v, want error %v", tt.amount, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDecimalPlusSign(t *testing.T) {
+	for _, s := range []string{"+162.00", "+.5", "+0"} {
+		got, err := parseDecimal(s)
+		if want, _ := strconv.ParseFloat(s[1:], 64); err != nil || got != want {
+			t.Errorf("parseDecimal(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"+", "++1", "+-1", "-+1", "1+"} {
+		if got, err := parseDecimal(s); err == nil {
+			t.Errorf("parseDecimal(%q) = %v; want error", s, got)
+		}
+	}
+}
+
+func TestAllowZeroAmount(t *testing.T) {
+	const records = "2025-01-01,Assets:Current,Expenses:Food,,Adjustment,0.00,GBP\n" +
+		"2025-01-02,Assets:Current,Expenses:Food,,Adjustment,-0.00,GBP\n"
+
+	crf := NewModuleCSVRecordFormat()
+
+	ts, err := TranslateCSV(strings.NewReader(records), crf, "", "")
+	if err == nil || len(ts) != 0 {
+		t.Errorf("TranslateCSV() of zero amounts = %v transactions, %v; want none and an error", len(ts), err)
+	}
+
+	crf.AllowZeroAmount = true
+
+	ts, err = TranslateCSV(strings.NewReader(records), crf, "", "")
+	if err != nil || len(ts) != 2 {
+		t.Fatalf("TranslateCSV() of zero amounts allowed = %v transactions, %v; want 2", len(ts), err)
+	}
+
+	for i, tr := range ts {
+		if tr.Amount != 0 || math.Signbit(tr.Amount) {
+			t.Errorf("transaction %v amount = %v, want 0", i, tr.Amount)
+		}
+	}
+}
+
+func TestSignedCreditDebit(t *testing.T) {
+	crf := CSVRecordFormat{
+		NFields: 4, DateI: 1, MemoI: 2, CreditI: 3, DebitI: 4, DateLayout: time.DateOnly,
+		SignedCreditDebit: true,
+	}
+	if err := crf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	const records = "2025-01-01,Refund,5.00,\n" +
+		"2025-01-02,Refund clawed back,-5.00,\n" +
+		"2025-01-03,Grocer,,16.92\n" +
+		"2025-01-04,Grocer reversed,,-16.92\n"
+
+	ts, err := TranslateCSV(strings.NewReader(records), crf, "Assets:Current", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []float64{5, -5, -16.92, 16.92}
+	if len(ts) != len(want) {
+		t.Fatalf("TranslateCSV() returned %v transactions, want %v", len(ts), len(want))
+	}
+
+	for i, w := range want {
+		if ts[i].Amount != w {
+			t.Errorf("transaction %v amount = %v, want %v", i, ts[i].Amount, w)
+		}
+	}
+
+	crf.SignedDebit = true
+	if err := crf.Validate(); err == nil {
+		t.Error("Validate() with signed debits and signed credits and debits = nil, want error")
+	}
+}
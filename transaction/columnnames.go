@@ -0,0 +1,100 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+A ColumnName maps a field of a CSV record format to the name of its column in a statement's header record
+e.g. field "DateI" to column "Transaction Date".
+Formats with column names find their field indexes from the header record of each statement,
+so they still work when a bank adds or reorders columns.
+*/
+type ColumnName struct {
+	Field string // The name of the field's index in the format e.g. "DateI".
+	Name  string // The name of the column in the header record, which is matched ignoring case.
+}
+
+var (
+	errColumnName        = errors.New("resolveColumnNames: column name in CSV record format must map an index field")
+	errColumnNameMissing = errors.New("resolveColumnNames: header record does not contain column")
+)
+
+// IndexFields returns pointers to the field indexes in this CSV record format by their names e.g. "DateI".
+func (crf *CSVRecordFormat) indexFields() map[string]*uint8 {
+	return map[string]*uint8{
+		"AmountI": &crf.AmountI, "BalanceI": &crf.BalanceI, "CodeI": &crf.CodeI, "CreditI": &crf.CreditI,
+		"CurrencyI": &crf.CurrencyI, "DateI": &crf.DateI, "DebitI": &crf.DebitI, "MemoI": &crf.MemoI,
+		"Memo2I": &crf.Memo2I, "Memo3I": &crf.Memo3I, "NoteI": &crf.NoteI, "OtherAccountI": &crf.OtherAccountI,
+		"PayeeI": &crf.PayeeI, "PriceI": &crf.PriceI, "ReferenceI": &crf.ReferenceI, "SignI": &crf.SignI,
+		"ThisAccountI": &crf.ThisAccountI,
+	}
+}
+
+/*
+ResolveColumnNames returns this CSV record format with its field indexes found from the header record
+by its column names, and its number of fields that of the header.
+The returned format has no column names, so it parses the records following the header.
+If the header does not contain a column, resolveColumnNames returns an error naming it.
+*/
+func (crf CSVRecordFormat) resolveColumnNames(header []string) (CSVRecordFormat, error) {
+	if maxNFields < len(header) {
+		return crf, errNFieldsRange
+	}
+
+	r := crf
+	r.ColumnNames, r.NFields = nil, uint8(len(header))
+	is := r.indexFields()
+
+	for _, cn := range crf.ColumnNames {
+		p, ok := is[cn.Field]
+		if !ok || strings.TrimSpace(cn.Name) == "" {
+			return crf, errColumnName
+		}
+
+		j := slices.IndexFunc(header, func(h string) bool {
+			return strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(cn.Name))
+		})
+		if j < 0 {
+			return crf, fmt.Errorf("%w: %q", errColumnNameMissing, cn.Name)
+		}
+
+		*p = uint8(j + 1)
+	}
+
+	return r, nil
+}
+
+/*
+ContainsThisAccount reports whether records in this CSV record format contain this account,
+either at its field index or in a column named by the format.
+*/
+func (crf CSVRecordFormat) ContainsThisAccount() bool {
+	return crf.ThisAccountI != 0 || slices.ContainsFunc(crf.ColumnNames, func(cn ColumnName) bool {
+		return cn.Field == "ThisAccountI"
+	})
+}
@@ -22,39 +22,51 @@ If not, see <https://www.gnu.org/licenses/>.
 package transaction
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"slices"
 	"strings"
+	"time"
 )
 
 const (
 	ModuleCSV = "mcsv" // The name of this module's CSV record format.
+
+	// The name of this module's CSV record format followed by a summary of monthly subtotals and totals.
+	ModuleCSVSummary = "mcsv-summary"
 )
 
 /*
 ParseCSV parses this transaction from the CSV record fields according to the format.
+Extra trailing fields that are empty are ignored, unless the format requires exactly NFields fields.
 It assumes the format is valid.
 The format can be verified by calling function crf.Validate.
 If ParseCSV fails to parse the transaction, it returns the first error.
+An error caused by the number of fields is an [NFieldsError],
+while one caused by the value of a field is a [FieldError], which names the field.
 */
 func (t *Transaction) ParseCSV(fields []string, crf CSVRecordFormat) error {
-	if len(fields) != int(crf.NFields) {
-		return errNFields
+	if !crf.ExactNFields {
+		fields = trimEmptyFields(fields, int(crf.NFields))
 	}
 
-	/*
-		Prepend fields with an empty string,
-		so a field whose index is zero has value empty string.
-	*/
-	fs := slices.Insert(fields, 0, "")
+	if len(fields) != int(crf.NFields) {
+		return NFieldsError{NFields: int(crf.NFields), Found: len(fields)}
+	}
 
-	err := t.parseRequired(fs, crf)
+	err := t.parseRequired(fields, crf)
 	if err != nil {
 		return err
 	}
 
-	err = t.parseOptional(fs, crf)
+	err = t.parseOptional(fields, crf)
 	if err != nil {
 		return err
 	}
@@ -62,17 +74,359 @@ func (t *Transaction) ParseCSV(fields []string, crf CSVRecordFormat) error {
 	return nil
 }
 
-// StringModuleCSV returns this transaction as this module's CSV record.
+/*
+Field returns the value of the field at the index, which starts at one, in the CSV record fields.
+A field whose index is zero is not contained in the record and has value empty string.
+Indexing fields this way, rather than prepending an empty string to them, avoids allocating for each record.
+*/
+func field(fields []string, i uint8) string {
+	if i == 0 {
+		return ""
+	}
+
+	return fields[i-1]
+}
+
+/*
+TrimEmptyFields returns the CSV record fields without extra trailing fields beyond n that are empty.
+If any extra field is not empty, the fields are returned unchanged.
+*/
+func trimEmptyFields(fields []string, n int) []string {
+	if len(fields) <= n {
+		return fields
+	}
+
+	for _, f := range fields[n:] {
+		if strings.TrimSpace(f) != "" {
+			return fields
+		}
+	}
+
+	return fields[:n]
+}
+
+// A LineError records the failure to parse a transaction from a line of input.
+type LineError struct {
+	Line int // The number of the line, starting at one.
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("%v on line %v", e.Err, e.Line)
+}
+
+func (e LineError) Unwrap() error {
+	return e.Err
+}
+
+/*
+An NFieldsError records that a CSV record does not have the number of fields of its format,
+which for every record of a statement usually means the format is wrong.
+*/
+type NFieldsError struct {
+	NFields int // The number of fields in the CSV record format.
+	Found   int // The number of fields in the record, without extra trailing fields that are empty.
+}
+
+func (e NFieldsError) Error() string {
+	return fmt.Sprintf("ParseCSV: CSV record format expects %v fields but record has %v", e.NFields, e.Found)
+}
+
+/*
+A FieldError records the failure to parse a transaction from the value of a field in a record,
+so that a changed record format can be debugged.
+*/
+type FieldError struct {
+	Field string // The name of the field e.g. "amount".
+	Index uint8  // The index of the field in the record, starting at one.
+	Value string // The field's value in the record.
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%v field (index %v) value %q: %v", e.Field, e.Index, e.Value, e.Err)
+}
+
+func (e FieldError) Unwrap() error {
+	return e.Err
+}
+
+// NewFieldError returns a FieldError for the field with the name and index in the CSV record fields.
+func newFieldError(name string, i uint8, fields []string, err error) FieldError {
+	return FieldError{Field: name, Index: i, Value: field(fields, i), Err: err}
+}
+
+/*
+StripBOM returns a reader for the text from r without its leading UTF-8 byte order mark (BOM), if any.
+Statements exported by some spreadsheet programs start with a BOM,
+which would otherwise become part of the first field in the first record.
+*/
+func StripBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+
+	bs, err := br.Peek(len(utf8BOM))
+	if err == nil && bytes.Equal(bs, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	return br
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF} // The UTF-8 encoding of the byte order mark U+FEFF.
+
+/*
+TranslateCSV reads CSV records from the reader,
+parses a transaction from each record according to the format then returns the transactions.
+It assumes the format is valid.
+This account, if not empty string, takes precedence over its field in the records.
+Currency, if not empty string, is used only for records whose currency field is empty or absent,
+so that a statement mixing currencies keeps the currency of each record.
+A first record matching the format's header is skipped.
+If the format has column names, the first record is the header from which the field indexes are found
+and, if it lacks a named column, TranslateCSV stops.
+If the format is fixed width, the records are lines whose fields are at the format's columns.
+
+If it fails to parse a transaction from a record, TranslateCSV skips the record then continues.
+If it fails to read a record, TranslateCSV stops.
+Either way, it returns the transactions parsed so far with all the errors joined.
+The error for a skipped record is a [LineError].
+A record whose amount has more decimal places than the format allows is not skipped,
+but is reported by a LineError too.
+*/
+func TranslateCSV(r io.Reader, crf CSVRecordFormat, thisAccount, currency string) ([]Transaction, error) {
+	return TranslateCSVContext(context.Background(), r, crf, thisAccount, currency, nil)
+}
+
+/*
+TranslateCSVContext is as TranslateCSV except that it stops before the next record once the context is done
+e.g. when a request embedding the translation times out.
+It then returns the transactions parsed so far with the context's error joined to the others.
+A read of a record already blocked on the reader is not interrupted.
+If parsed is not nil, it is called with each transaction and the number of the line it starts on
+as soon as the transaction is parsed, before the next record is read e.g. to log it.
+*/
+func TranslateCSVContext(ctx context.Context, r io.Reader, crf CSVRecordFormat,
+	thisAccount, currency string, parsed func(t Transaction, line int),
+) ([]Transaction, error) {
+	var read recordReader
+
+	if crf.IsFixedWidth() {
+		read = newFixedWidthRecordReader(r, crf)
+	} else {
+		read = newCSVRecordReader(r, crf)
+	}
+
+	var (
+		errs []error
+		ts   []Transaction
+	)
+
+	hfs, _ := crf.headerFields()
+
+	for first := true; ; first = false {
+		if ctx.Err() != nil {
+			errs = append(errs, fmt.Errorf("TranslateCSVContext: %w", ctx.Err()))
+
+			break
+		}
+
+		fs, n, err := read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("TranslateCSV: %w", err))
+
+			break
+		}
+
+		if first && len(crf.ColumnNames) != 0 {
+			crf, err = crf.resolveColumnNames(fs)
+			if err != nil {
+				errs = append(errs, LineError{Line: n, Err: err})
+
+				break
+			}
+
+			continue
+		}
+
+		if first && hfs != nil && slices.Equal(fs, hfs) {
+			continue
+		}
+
+		var t Transaction
+
+		t.Currency, t.ThisAccount = currency, thisAccount
+
+		err = t.ParseCSV(fs, crf)
+		if err != nil {
+			errs = append(errs, LineError{Line: n, Err: err})
+
+			continue
+		}
+
+		err = crf.checkDecimalPlaces(fs)
+		if err != nil {
+			errs = append(errs, LineError{Line: n, Err: err})
+		}
+
+		if parsed != nil {
+			parsed(t, n)
+		}
+
+		ts = append(ts, t)
+	}
+
+	return ts, errors.Join(errs...)
+}
+
+/*
+A recordReader returns the fields of the next record in a statement and the number of the line it starts on.
+At the end of the statement, it returns error io.EOF.
+*/
+type recordReader func() ([]string, int, error)
+
+/*
+NewCSVRecordReader returns a reader of CSV records from r, without its leading UTF-8 BOM,
+whose quotes are read lazily and comment lines skipped if the CSV record format allows it.
+The format's leading columns to ignore are dropped from each record.
+*/
+func newCSVRecordReader(r io.Reader, crf CSVRecordFormat) recordReader {
+	cr := csv.NewReader(StripBOM(r))
+	/*
+		The number of fields in a record is checked by ParseCSV,
+		so disable the reader's check.
+	*/
+	cr.FieldsPerRecord, cr.ReuseRecord = -1, true
+	cr.LazyQuotes = crf.LazyQuotes
+
+	if crf.CommentChar != "" {
+		cr.Comment = []rune(crf.CommentChar)[0]
+	}
+
+	return func() ([]string, int, error) {
+		fs, err := cr.Read()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		n, _ := cr.FieldPos(0)
+
+		return fs[min(len(fs), int(crf.IgnoreLeadingColumns)):], n, nil
+	}
+}
+
+/*
+StringModuleCSV returns this transaction as this module's CSV record, with its date in OutputDateLayout.
+Fields containing a comma, quote or line break are quoted, so that the record can be parsed again.
+*/
 func (t Transaction) StringModuleCSV() string {
+	return t.stringModuleCSV(OutputDateLayout)
+}
+
+// StringModuleCSV returns this transaction as this module's CSV record with its date in the layout.
+func (t Transaction) stringModuleCSV(dateLayout string) string {
 	a := stringAmount(t.Amount)
-	fs := []string{t.Date, t.ThisAccount, t.OtherAccount, t.Code, t.Memo, a, t.Currency}
+	fs := []string{stringDate(t.Date, dateLayout), t.ThisAccount, t.OtherAccount, t.Code, t.Memo, a, t.Currency}
+
+	var sb strings.Builder
+
+	cw := csv.NewWriter(&sb)
+	_ = cw.Write(fs) // Writing to a strings.Builder cannot fail.
+	cw.Flush()
+
+	return sb.String()
+}
+
+/*
+RoundTripCSV checks that a record in the CSV record format survives translation to this module's CSV record.
+It translates the record, as TranslateCSV does with this account and currency,
+writes the transaction as this module's CSV record then parses that record with NewModuleCSVRecordFormat.
+Fields that this module's CSV record does not contain, such as the balance, note, payee, price and reference,
+are not compared.
+If it fails to parse either record, or the transactions are not equal, RoundTripCSV returns the error.
+
+RoundTripCSV is intended for checking new CSV record formats against sample records from their statements.
+*/
+func RoundTripCSV(record string, crf CSVRecordFormat, thisAccount, currency string) error {
+	ts, err := TranslateCSV(strings.NewReader(record), crf, thisAccount, currency)
+	if err != nil {
+		return fmt.Errorf("RoundTripCSV: %w", err)
+	} else if len(ts) != 1 {
+		return errRoundTripN
+	}
+
+	want := ts[0]
+	want.Balance, want.Note, want.Payee, want.Price, want.Tags = nil, "", "", "", nil
+
+	mcsv := want.stringModuleCSV(time.DateOnly)
+
+	mcrf := NewModuleCSVRecordFormat()
+	mcrf.AllowZeroAmount = crf.AllowZeroAmount
+
+	got, err := TranslateCSV(strings.NewReader(mcsv), mcrf, "", "")
+	if err != nil {
+		return fmt.Errorf("RoundTripCSV: %w", err)
+	}
+
+	if !want.Equal(got[0]) {
+		return fmt.Errorf("%w: %q became %q", errRoundTrip,
+			strings.TrimSuffix(want.StringModuleCSV(), "\n"), strings.TrimSuffix(got[0].StringModuleCSV(), "\n"))
+	}
+
+	return nil
+}
+
+/*
+StringModuleCSVSummary returns a summary of the transactions' amounts as CSV records.
+For each month and currency, there is a subtotal record e.g. "1982-10,subtotal,75.36,GBP",
+ordered by month then currency.
+A transaction whose date is not in this module's layout e.g. empty string is subtotalled under month "unknown".
+For each currency, there is a total record e.g. "total,75.36,GBP", ordered by currency.
+Amounts in different currencies are never added together.
+*/
+func StringModuleCSVSummary(ts []Transaction) string {
+	type key struct{ month, currency string }
+
+	var (
+		subtotals = make(map[key]float64)
+		totals    = make(map[string]float64)
+	)
+
+	for _, t := range ts {
+		m := "unknown"
+		if d, err := time.Parse(time.DateOnly, t.Date); err == nil {
+			m = d.Format("2006-01")
+		}
+
+		subtotals[key{m, t.Currency}] += t.Amount
+		totals[t.Currency] += t.Amount
+	}
+
+	ks := slices.SortedFunc(maps.Keys(subtotals), func(a, b key) int {
+		return cmp.Or(strings.Compare(a.month, b.month), strings.Compare(a.currency, b.currency))
+	})
+
+	var sb strings.Builder
+
+	for _, k := range ks {
+		fmt.Fprintf(&sb, "%v,subtotal,%v,%v\n", k.month, stringAmount(roundAmount(subtotals[k])), k.currency)
+	}
+
+	for _, cu := range slices.Sorted(maps.Keys(totals)) {
+		fmt.Fprintf(&sb, "total,%v,%v\n", stringAmount(roundAmount(totals[cu])), cu)
+	}
 
-	return strings.Join(fs, ",") + "\n"
+	return sb.String()
 }
 
+// The key of the tag keeping a transaction's reference field e.g. "; ref: 18832946" in a Ledger journal entry.
+const referenceTag = "ref"
+
 var (
 	errMemo        = errors.New("parseRequired: memo cannot be empty string")
-	errNFields     = errors.New("ParseCSV: unexpected number of fields in CSV record")
+	errRoundTrip   = errors.New("RoundTripCSV: transaction changed on its round trip through this module's CSV record")
+	errRoundTripN  = errors.New("RoundTripCSV: record must contain exactly one transaction")
 	errThisAccount = errors.New(
 		"parseRequired: this account cannot be empty string or \"" + DefaultOtherAccount + "\"")
 )
@@ -85,30 +439,38 @@ func (t *Transaction) parseRequired(fields []string, crf CSVRecordFormat) error
 		return err
 	}
 
-	t.Date, err = ParseDate(fields[crf.DateI], crf.DateLayout)
+	t.Date, err = crf.parseDate(field(fields, crf.DateI))
 	if err != nil {
-		return err
+		return newFieldError("date", crf.DateI, fields, err)
+	}
+
+	t.Memo = crf.replaceMemo(crf.memoField(fields))
+	if crf.CleanMemo {
+		t.Memo = strings.Join(strings.Fields(t.Memo), " ")
 	}
 
-	t.Memo = fields[crf.MemoI]
 	if t.Memo == "" {
-		return errMemo
+		return newFieldError("memo", crf.MemoI, fields, errMemo)
 	}
 
-	t.OtherAccount = fields[crf.OtherAccountI]
+	t.OtherAccount = crf.normaliseAccount(field(fields, crf.OtherAccountI))
 	if t.OtherAccount == "" {
 		t.OtherAccount = DefaultOtherAccount
 	}
 
-	a := fields[crf.ThisAccountI]
+	a := crf.normaliseAccount(field(fields, crf.ThisAccountI))
 
 	switch {
-	case t.ThisAccount == DefaultOtherAccount || a == DefaultOtherAccount:
+	case t.ThisAccount == DefaultOtherAccount:
 		return errThisAccount
+	case a == DefaultOtherAccount:
+		return newFieldError("this account", crf.ThisAccountI, fields, errThisAccount)
 	case t.ThisAccount != "":
 		// This account already has a value which takes precedence over its field.
 	case a != "":
 		t.ThisAccount = a
+	case crf.ThisAccountI != 0:
+		return newFieldError("this account", crf.ThisAccountI, fields, errThisAccount)
 	default:
 		return errThisAccount
 	}
@@ -116,21 +478,90 @@ func (t *Transaction) parseRequired(fields []string, crf CSVRecordFormat) error
 	return nil
 }
 
+/*
+MemoField returns the memo field of the CSV record fields.
+If this CSV record format has memo continuations, memoField returns the memo and continuations trimmed
+and joined by the format's memo separator, skipping those that are empty.
+*/
+func (crf CSVRecordFormat) memoField(fields []string) string {
+	if crf.Memo2I == 0 && crf.Memo3I == 0 {
+		return field(fields, crf.MemoI)
+	}
+
+	var ms []string
+
+	for _, i := range []uint8{crf.MemoI, crf.Memo2I, crf.Memo3I} {
+		if m := strings.TrimSpace(field(fields, i)); m != "" {
+			ms = append(ms, m)
+		}
+	}
+
+	return strings.Join(ms, cmp.Or(crf.MemoSeparator, " "))
+}
+
+/*
+NormaliseAccount returns the account field with its white space removed and upper-cased,
+if this CSV record format's account fields are account numbers.
+Otherwise, normaliseAccount returns the field unchanged.
+*/
+func (crf CSVRecordFormat) normaliseAccount(field string) string {
+	if !crf.NormaliseAccountNumbers {
+		return field
+	}
+
+	return strings.ToUpper(strings.Join(strings.Fields(field), ""))
+}
+
 func (t *Transaction) parseOptional(fields []string, crf CSVRecordFormat) error {
-	t.Code = fields[crf.CodeI]
+	t.Code, t.Note = field(fields, crf.CodeI), field(fields, crf.NoteI)
+	t.Payee = strings.TrimSpace(field(fields, crf.PayeeI))
 
-	if t.Currency != "" {
-		// The existing currency value takes precedence over its field.
-		return nil
+	if ref := strings.TrimSpace(field(fields, crf.ReferenceI)); ref != "" {
+		t.SetTag(referenceTag, ref)
 	}
 
-	cu := fields[crf.CurrencyI]
+	b := crf.amountField(fields, crf.BalanceI)
+	if b != "" {
+		n, err := parseDecimal(b)
+		if err != nil {
+			return newFieldError("balance", crf.BalanceI, fields, err)
+		}
+
+		n = crf.fromMinorUnits(n)
+		t.Balance = &n
+	}
+
+	t.Price = strings.TrimSpace(field(fields, crf.PriceI))
+	if t.Price != "" {
+		_, _, err := parseLedgerAmount(t.Price)
+		if err != nil {
+			return newFieldError("price", crf.PriceI, fields, err)
+		}
+	}
+
+	if crf.CodeI == 0 {
+		t.Code = crf.CreditCode
+		if t.Amount < 0 {
+			t.Code = crf.DebitCode
+		}
+	}
+
+	cu := field(fields, crf.CurrencyI)
 	if cu == "" {
+		if ac := crf.amountCurrency(fields); ac != "" {
+			t.Currency = ac
+		}
+
+		// Otherwise, the existing currency value, if any, is the fallback for records without one.
 		return nil
 	}
 
 	if !IsLedgerCurrency(cu) {
-		return fmt.Errorf("parseOptional: %w", errCurrency)
+		return newFieldError("currency", crf.CurrencyI, fields, fmt.Errorf("parseOptional: %w", errCurrency))
+	}
+
+	if crf.StrictCurrency && !IsStrictCurrency(cu) {
+		return newFieldError("currency", crf.CurrencyI, fields, fmt.Errorf("parseOptional: %w", errStrictCurrency))
 	}
 
 	t.Currency = cu
@@ -0,0 +1,146 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func BenchmarkParseCSV(b *testing.B) {
+	crf := NewModuleCSVRecordFormat()
+	fields := []string{"2025-01-01", "Assets:Current", "Expenses:Food", "", "Grocer", "-16.92", "GBP"}
+
+	b.ReportAllocs()
+
+	for b.Loop() {
+		var t Transaction
+
+		err := t.ParseCSV(fields, crf)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTranslateCSVCurrencyPrecedence(t *testing.T) {
+	const records = "2025-01-01,Assets:Current,Expenses:Food,,Grocer,-16.92,GBP\n" +
+		"2025-01-02,Assets:Current,Expenses:Food,,Cafe,-3.20,\n"
+
+	ts, err := TranslateCSV(strings.NewReader(records), NewModuleCSVRecordFormat(), "", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"GBP", "EUR"}
+	if len(ts) != len(want) {
+		t.Fatalf("TranslateCSV() returned %v transactions, want %v", len(ts), len(want))
+	}
+
+	for i, tr := range ts {
+		if tr.Currency != want[i] {
+			t.Errorf("transaction %v currency = %q, want %q", i, tr.Currency, want[i])
+		}
+	}
+}
+
+func TestStringModuleCSVQuotes(t *testing.T) {
+	tr := Transaction{
+		Date: "2025-01-01", ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+		Memo: `Grocer, "Fresh" & Co`, Amount: -16.92, Currency: "GBP",
+	}
+
+	want := `2025-01-01,Assets:Current,Expenses:Food,,"Grocer, ""Fresh"" & Co",-16.92,GBP` + "\n"
+	if got := tr.StringModuleCSV(); got != want {
+		t.Errorf("StringModuleCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestRoundTripCSV(t *testing.T) {
+	crf := NewModuleCSVRecordFormat()
+
+	tests := []struct {
+		record  string
+		wantErr bool
+	}{
+		{"2025-01-01,Assets:Current,Expenses:Food,,Grocer,-16.92,GBP\n", false},
+		{`2025-01-01,Assets:Current,Expenses:Food,,"Grocer, ""Fresh"" & Co",-16.92,GBP` + "\n", false},
+		{"2025-01-01,Assets:Current,Expenses:Food,,Grocer,-16.92,GBP\n" +
+			"2025-01-02,Assets:Current,Expenses:Food,,Cafe,-3.20,GBP\n", true},
+		{"not,a,record\n", true},
+	}
+
+	for _, tt := range tests {
+		err := RoundTripCSV(tt.record, crf, "", "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("RoundTripCSV(%q) error = %v, want error %v", tt.record, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTranslateCSVContextParsed(t *testing.T) {
+	const records = "2025-01-01,Assets:Current,Expenses:Food,,Grocer,-16.92,GBP\n" +
+		"2025-01-02,Assets:Current,Expenses:Food,,Cafe,not an amount,GBP\n" +
+		"2025-01-03,Assets:Current,Expenses:Food,,Bakery,-2.10,GBP\n"
+
+	var (
+		lines []int
+		memos []string
+	)
+
+	ts, err := TranslateCSVContext(context.Background(), strings.NewReader(records), NewModuleCSVRecordFormat(), "", "",
+		func(t Transaction, line int) {
+			lines, memos = append(lines, line), append(memos, t.Memo)
+		})
+	if err == nil {
+		t.Error("TranslateCSVContext() error = nil, want error for line 2")
+	}
+
+	if want := []int{1, 3}; !slices.Equal(lines, want) {
+		t.Errorf("parsed called for lines %v, want %v", lines, want)
+	}
+
+	if want := []string{"Grocer", "Bakery"}; !slices.Equal(memos, want) || len(ts) != len(want) {
+		t.Errorf("parsed called for memos %q with %v transactions returned, want %q", memos, len(ts), want)
+	}
+}
+
+func TestStringModuleCSVSummary(t *testing.T) {
+	ts := []Transaction{
+		{Date: "1982-10-03", Amount: 75, Currency: "GBP"},
+		{Date: "1982-10-20", Amount: 0.36, Currency: "GBP"},
+		{Date: "1982-11-01", Amount: 5, Currency: "EUR"},
+		{Amount: -1, Currency: "GBP"},
+		{Date: "1982", Amount: -2, Currency: "GBP"},
+	}
+
+	want := "1982-10,subtotal,75.36,GBP\n" +
+		"1982-11,subtotal,5,EUR\n" +
+		"unknown,subtotal,-3,GBP\n" +
+		"total,5,EUR\n" +
+		"total,72.36,GBP\n"
+	if got := StringModuleCSVSummary(ts); got != want {
+		t.Errorf("StringModuleCSVSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -22,17 +22,45 @@ If not, see <https://www.gnu.org/licenses/>.
 package transaction
 
 import (
+	"bytes"
+	"cmp"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // A CSVRecordFormat defines the format of CSV records representing financial transactions.
 type CSVRecordFormat struct {
 	NFields uint8 // The number of fields in a record.
 
+	// The optional header record of statements e.g. "Date,Memo,Amount",
+	// which is skipped rather than parsed and identifies the format to DetectCSVRecordFormat.
+	Header string
+
+	// The optional columns of the fields in fixed-width records, which are lines of text rather than CSV records.
+	// If there are columns, there must be one for each field.
+	Columns []Column `xml:"Columns>Column"`
+
+	// Optional names of the columns of fields in the statement's header record, instead of their indexes.
+	// If there are column names, the first record of each statement must be a header containing them
+	// and the number of fields is that of the header.
+	ColumnNames []ColumnName `xml:"ColumnNames>ColumnName"`
+
 	// The indexes of fields in the record.
 	// Some fields are required, while the rest are optional.
 	// The index for a required field is between 1 and NFields inclusive.
@@ -41,48 +69,356 @@ type CSVRecordFormat struct {
 	// Either amount, or both credit and debit are required.
 	AmountI         uint8 // Either this field is required or
 	CreditI, DebitI uint8 // these two.
+	SignI           uint8 // Whether an unsigned amount is a credit or debit: "CR" or "C", or "DR" or "D".
+	BalanceI        uint8 // The balance of this account after the transaction.
+	PriceI          uint8 // The price of one unit of the currency as a Ledger amount e.g. "0.85 GBP".
 	CurrencyI       uint8
 	CodeI           uint8
 	DateI           uint8 // This field is required.
 	MemoI           uint8 // This field is required.
+	Memo2I, Memo3I  uint8 // Optional continuations of the memo e.g. "Narrative2", joined to it by MemoSeparator.
+	NoteI           uint8 // A free-text annotation, which is written as a Ledger comment.
+	PayeeI          uint8 // Whom the transaction paid or was paid by, which is written as the Ledger payee.
+	ReferenceI      uint8 // The bank's unique reference for the transaction, which is kept as tag "ref".
 	OtherAccountI   uint8
 	ThisAccountI    uint8
 
+	// The Ledger name of this account for statements whose records do not contain it e.g. "Assets:Current".
+	ThisAccountName string
+
+	// Whether this and other account fields are account numbers such as IBANs,
+	// which are normalised by removing white space and upper-casing e.g. "gb29 nwbk 6016" becomes "GB29NWBK6016".
+	NormaliseAccountNumbers bool
+
 	// The Go-style date layout in the records e.g. "01/02/2006".
 	DateLayout string
+	// Optional further date layouts, which are tried in order if a date does not match DateLayout.
+	DateLayouts []string `xml:"DateLayouts>DateLayout"`
+	// Optional month names substituted in dates before they are parsed e.g. "janvier" by "January".
+	MonthNames []MonthName `xml:"MonthNames>MonthName"`
+
+	// Optional replacements applied in order to memos e.g. of "^POS PURCHASE [0-9]+ " by "".
+	MemoReplacements []MemoReplacement `xml:"MemoReplacements>MemoReplacement"`
+	// Whether memos are trimmed and repeated white space in them is collapsed to a single space.
+	CleanMemo bool
+	// The optional separator joining the memo and its continuations e.g. " / ", which defaults to a single space.
+	MemoSeparator string
+
+	// Whether quotes in records are read leniently, as by [csv.Reader] with LazyQuotes,
+	// e.g. "5" Main St" rather than "5"" Main St", instead of the record failing to be read.
+	// Single quotes are not special in CSV records, so they are always part of their fields.
+	LazyQuotes bool
+
+	// The optional character starting comment lines e.g. "#" for metadata lines,
+	// which are skipped rather than reported as records that cannot be parsed.
+	CommentChar string
+
+	// The optional number of leading columns dropped from each CSV record before its fields are indexed
+	// e.g. 1 for an export's row number, so that the same indexes suit exports with and without it.
+	// The number of fields, header and column names do not include these columns.
+	IgnoreLeadingColumns uint8
+
+	// Whether records must have exactly NFields fields.
+	// By default, extra trailing fields that are empty are ignored.
+	ExactNFields bool
+
+	// Whether currency fields must be a known symbol or a three-letter upper-case code e.g. "$" or "GBP".
+	// By default, any Ledger currency is accepted.
+	StrictCurrency bool
+
+	// Whether amount, credit, debit and balance fields may contain a currency symbol, code or both
+	// e.g. "$162.00", "162.00 NZD" or "NZD $162.00".
+	// The currency is removed from the number and, if the transaction has no other currency, becomes its currency,
+	// preferring the code to the symbol.
+	CurrencyInAmount bool
+
+	// Whether debit fields may already have a minus sign e.g. "-16.92" rather than "16.92".
+	SignedDebit bool
+
+	// Whether credit and debit fields are signed, so that a negative value reverses the field's direction
+	// e.g. credit "-5.00" for a refund that was clawed back is an outflow, as is debit "5.00",
+	// while debit "-5.00" is an inflow.
+	// By default, credit and debit fields must be positive.
+	SignedCreditDebit bool
+
+	// Whether transactions may have a zero amount e.g. "0.00" for an adjusting entry with a note.
+	// By default, a zero amount is rejected.
+	AllowZeroAmount bool
+
+	// The optional maximum number of decimal places in amount, credit and debit fields e.g. 2 for cents.
+	// An amount with more places is reported, as it may be a misread field, but not skipped.
+	MaxDecimalPlaces uint8
+
+	// The optional number of decimal places implied by amount, credit, debit and balance fields in minor units,
+	// such as integer cents e.g. 2 for "16200" meaning 162.00.
+	// Each such number is divided by ten to this power.
+	MinorUnitDigits uint8
+
+	// Optional codes for credits and debits e.g. "CR" and "DR", if the records do not contain a code field.
+	// A transaction's code is chosen by the sign of its amount.
+	CreditCode, DebitCode string
+
+	// The separators in amount, credit and debit fields e.g. "," and "." for "1.234,56".
+	// The decimal separator defaults to "." if empty, while the thousands separator is optional.
+	DecimalSeparator   string
+	ThousandsSeparator string
+}
+
+/*
+A MonthName pairs a local month name, or its abbreviation, with the English name understood by Go date layouts.
+Names are case sensitive e.g. "janv." and "Jan".
+*/
+type MonthName struct {
+	Local   string
+	English string
+}
+
+/*
+A MemoReplacement replaces each match of its regular expression in a memo by its replacement,
+in which "$1" stands for the text matching the first parenthesised subexpression and so on.
+*/
+type MemoReplacement struct {
+	Pattern     string
+	Replacement string
+
+	re *regexp.Regexp
 }
 
 /*
-NewCSVRecordFormat returns a valid CSV record format read from the named XML file.
-The format's date layout defaults to "2006-01-02", while all other fields default to zero.
+NewCSVRecordFormat returns a valid CSV record format read from the named XML file,
+or JSON file if the name has extension ".json".
+The format's date layout defaults to "2006-01-02" and its decimal separator to ".",
+while all other fields default to zero.
 If it fails to read or validate the format, NewCSVRecordFormat returns the first error.
+An element or object member in the file that does not name a field of the format is an error,
+rather than being ignored, so that misspelt names are found.
+
+In JSON, the format's fields are members of an object e.g. {"NFields": 3, "DateI": 1, "DateLayouts": ["02/01/2006"]}.
 */
 func NewCSVRecordFormat(fileName string) (CSVRecordFormat, error) {
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return CSVRecordFormat{}, fmt.Errorf("NewCSVRecordFormat: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(fileName), ".json") {
+		return parseJSONCSVRecordFormat(bs)
+	}
+
+	return parseCSVRecordFormat(bs)
+}
+
+/*
+LoadCSVRecordFormat returns a valid CSV record format by name.
+If the name is that of a built-in format e.g. "mcsv", LoadCSVRecordFormat returns that format.
+If not, it returns the format read from the named XML file by NewCSVRecordFormat.
+If it fails to read or validate the format, LoadCSVRecordFormat returns the first error.
+*/
+func LoadCSVRecordFormat(name string) (CSVRecordFormat, error) {
+	bs, err := builtInFormats.ReadFile(path.Join(builtInFormatsDir, name+".xml"))
+	if err != nil {
+		return NewCSVRecordFormat(name)
+	}
+
+	return parseCSVRecordFormat(bs)
+}
+
+// BuiltInCSVRecordFormatNames returns the names of the built-in CSV record formats in lexical order.
+func BuiltInCSVRecordFormatNames() []string {
+	des, _ := builtInFormats.ReadDir(builtInFormatsDir)
+
+	var ns []string
+
+	for _, de := range des {
+		ns = append(ns, strings.TrimSuffix(de.Name(), ".xml"))
+	}
+
+	return ns
+}
+
+/*
+The built-in CSV record formats, which are XML files named after their formats
+in directory builtInFormatsDir.
+*/
+//go:embed formats/*.xml
+var builtInFormats embed.FS
+
+const builtInFormatsDir = "formats"
+
+/*
+ParseCSVRecordFormat returns a valid CSV record format parsed from the XML document.
+If it fails to parse or validate the format, parseCSVRecordFormat returns the first error.
+*/
+func parseCSVRecordFormat(bs []byte) (CSVRecordFormat, error) {
 	var crf CSVRecordFormat
 
-	bs, err := os.ReadFile(fileName)
+	err := checkXMLElements(bs)
 	if err != nil {
-		return crf, fmt.Errorf("NewCSVRecordFormat: %w", err)
+		return crf, err
 	}
 
 	err = xml.Unmarshal(bs, &crf)
 	if err != nil {
-		return crf, fmt.Errorf("NewCSVRecordFormat: %w", err)
+		return crf, fmt.Errorf("parseCSVRecordFormat: %w", err)
 	}
 
-	if crf.DateLayout == "" {
-		crf.DateLayout = time.DateOnly
+	crf.setDefaults()
+
+	err = crf.Validate()
+	if err != nil {
+		return crf, err
 	}
 
+	crf.compileMemoReplacements()
+
+	return crf, nil
+}
+
+/*
+ParseJSONCSVRecordFormat returns a valid CSV record format parsed from the JSON document.
+If it fails to parse or validate the format, parseJSONCSVRecordFormat returns the first error.
+*/
+func parseJSONCSVRecordFormat(bs []byte) (CSVRecordFormat, error) {
+	var crf CSVRecordFormat
+
+	d := json.NewDecoder(bytes.NewReader(bs))
+	d.DisallowUnknownFields()
+
+	err := d.Decode(&crf)
+	if err != nil {
+		return crf, fmt.Errorf("parseJSONCSVRecordFormat: %w", err)
+	}
+
+	crf.setDefaults()
+
 	err = crf.Validate()
 	if err != nil {
 		return crf, err
 	}
 
+	crf.compileMemoReplacements()
+
+	return crf, nil
+}
+
+/*
+ParseCSVRecordFormatSpec returns a valid CSV record format parsed from a compact specification,
+which is a comma-separated list of key=value pairs e.g. "date=2,memo=3,amount=15,dateLayout=02-01-2006,nfields=16".
+It is for a quick one-off translation or exploring a statement without writing an XML file.
+The keys are case insensitive.
+Key nfields is the number of fields, minorUnitDigits the decimal places implied by amounts in minor units
+and ignoreLeadingColumns the number of leading columns dropped from each record,
+while the keys of field indexes are the format's index names without their
+final I e.g. date for DateI and thisAccount for ThisAccountI.
+The string keys are dateLayout, thisAccountName, creditCode, debitCode, commentChar, decimalSeparator,
+thousandsSeparator and memoSeparator, so a separator cannot be a comma.
+If it fails to parse or validate the format, ParseCSVRecordFormatSpec returns the first error,
+which names any unknown key.
+*/
+func ParseCSVRecordFormatSpec(spec string) (CSVRecordFormat, error) {
+	var crf CSVRecordFormat
+
+	numbers := map[string]*uint8{
+		"nfields": &crf.NFields, "minorunitdigits": &crf.MinorUnitDigits,
+		"ignoreleadingcolumns": &crf.IgnoreLeadingColumns,
+	}
+	for n, p := range crf.indexFields() {
+		numbers[strings.ToLower(strings.TrimSuffix(n, "I"))] = p
+	}
+
+	texts := map[string]*string{
+		"datelayout": &crf.DateLayout, "thisaccountname": &crf.ThisAccountName, "creditcode": &crf.CreditCode,
+		"debitcode": &crf.DebitCode, "commentchar": &crf.CommentChar, "decimalseparator": &crf.DecimalSeparator,
+		"thousandsseparator": &crf.ThousandsSeparator, "memoseparator": &crf.MemoSeparator,
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return crf, fmt.Errorf("%w: %q", errSpecPair, pair)
+		}
+
+		k = strings.ToLower(strings.TrimSpace(k))
+
+		if p, ok := numbers[k]; ok {
+			n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 8)
+			if err != nil {
+				return crf, fmt.Errorf("ParseCSVRecordFormatSpec: key %v: %w", k, err)
+			}
+
+			*p = uint8(n)
+
+			continue
+		}
+
+		p, ok := texts[k]
+		if !ok {
+			return crf, fmt.Errorf("%w: %q", errSpecKey, k)
+		}
+
+		*p = v
+	}
+
+	crf.setDefaults()
+
+	err := crf.Validate()
+	if err != nil {
+		return crf, err
+	}
+
 	return crf, nil
 }
 
-// NewModuleCSVRecordFormat returns this module's CSV record format.
+/*
+CompileMemoReplacements compiles the patterns of this CSV record format's memo replacements,
+so that they are not compiled for each record.
+It assumes the patterns are valid.
+*/
+func (crf *CSVRecordFormat) compileMemoReplacements() {
+	mrs := slices.Clone(crf.MemoReplacements)
+	for i := range mrs {
+		mrs[i].re = regexp.MustCompile(mrs[i].Pattern)
+	}
+
+	crf.MemoReplacements = mrs
+}
+
+/*
+ReplaceMemo returns the memo with this CSV record format's replacements applied in order.
+The result is trimmed of leading and trailing white space.
+*/
+func (crf CSVRecordFormat) replaceMemo(memo string) string {
+	if len(crf.MemoReplacements) == 0 {
+		return memo
+	}
+
+	for _, mr := range crf.MemoReplacements {
+		re := mr.re
+		if re == nil {
+			re = regexp.MustCompile(mr.Pattern)
+		}
+
+		memo = re.ReplaceAllString(memo, mr.Replacement)
+	}
+
+	return strings.TrimSpace(memo)
+}
+
+// SetDefaults sets the date layout and decimal separator of this CSV record format, if they are empty.
+func (crf *CSVRecordFormat) setDefaults() {
+	if crf.DateLayout == "" {
+		crf.DateLayout = time.DateOnly
+	}
+
+	if crf.DecimalSeparator == "" {
+		crf.DecimalSeparator = "."
+	}
+}
+
+/*
+NewModuleCSVRecordFormat returns this module's CSV record format.
+It is also the built-in format named ModuleCSV.
+*/
 func NewModuleCSVRecordFormat() CSVRecordFormat {
 	return CSVRecordFormat{
 		NFields: 7,
@@ -96,17 +432,118 @@ func NewModuleCSVRecordFormat() CSVRecordFormat {
 		CurrencyI:     7,
 
 		DateLayout: time.DateOnly,
+
+		DecimalSeparator: ".",
+	}
+}
+
+/*
+CheckXMLElements returns nil if each child of the root element in the XML document
+names a field of type CSVRecordFormat.
+If not, checkXMLElements returns an error naming the first unknown element.
+*/
+func checkXMLElements(bs []byte) error {
+	known := make(map[string]bool)
+
+	for _, f := range reflect.VisibleFields(reflect.TypeFor[CSVRecordFormat]()) {
+		if !f.IsExported() {
+			continue
+		}
+
+		n, _, _ := strings.Cut(f.Tag.Get("xml"), ">")
+		if n == "" {
+			n = f.Name
+		}
+
+		known[n] = true
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(bs))
+
+	for depth := 0; ; {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("checkXMLElements: %w", err)
+		}
+
+		switch el := tok.(type) {
+		case xml.StartElement:
+			depth++
+
+			if depth == 2 && !known[el.Name.Local] {
+				ln, _ := d.InputPos()
+
+				return fmt.Errorf("checkXMLElements: unknown element <%v> in CSV record format on line %v",
+					el.Name.Local, ln)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+/*
+WriteXML writes this CSV record format in indented XML,
+which can be read by NewCSVRecordFormat.
+If it fails to write the format, WriteXML returns the error.
+*/
+func (crf CSVRecordFormat) WriteXML(w io.Writer) error {
+	bs, err := xml.MarshalIndent(crf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("WriteXML: %w", err)
 	}
+
+	_, err = fmt.Fprintf(w, "%s\n", bs)
+	if err != nil {
+		return fmt.Errorf("WriteXML: %w", err)
+	}
+
+	return nil
+}
+
+/*
+A FormatError reports which field of a CSV record format is invalid e.g. "DateLayout",
+so that a program, such as a form for editing formats, can show the error against that field.
+Field is named as in XML and the error wrapped is the reason.
+*/
+type FormatError struct {
+	Field string // The name of the field in the format e.g. "DateI".
+	Err   error
+}
+
+func (e FormatError) Error() string {
+	return fmt.Sprintf("%v field: %v", e.Field, e.Err)
+}
+
+func (e FormatError) Unwrap() error {
+	return e.Err
 }
 
 /*
 Validate returns nil if this CSV record format is valid.
-If not, Validate returns the first error.
+If not, Validate returns the first error, which is a [FormatError] naming the invalid field.
 */
 func (crf CSVRecordFormat) Validate() error {
+	if len(crf.ColumnNames) != 0 {
+		// Validate the format as if the header contained only its column names.
+		names := make([]string, len(crf.ColumnNames))
+		for i, cn := range crf.ColumnNames {
+			names[i] = cn.Name
+		}
+
+		r, err := crf.resolveColumnNames(names)
+		if err != nil {
+			return FormatError{Field: "ColumnNames", Err: err}
+		}
+
+		return r.Validate()
+	}
+
 	n := crf.NFields
 	if n < minNFields || maxNFields < n {
-		return errNFieldsRange
+		return FormatError{Field: "NFields", Err: errNFieldsRange}
 	}
 
 	err := crf.validateIndexes()
@@ -120,28 +557,101 @@ func (crf CSVRecordFormat) Validate() error {
 	}
 
 	if !IsDateLayout(crf.DateLayout) {
-		return errDateLayout
+		return FormatError{Field: "DateLayout", Err: errDateLayout}
+	}
+
+	for _, dl := range crf.DateLayouts {
+		if !IsDateLayout(dl) {
+			return FormatError{Field: "DateLayouts", Err: errDateLayout}
+		}
+	}
+
+	for _, mn := range crf.MonthNames {
+		if mn.Local == "" || mn.English == "" {
+			return FormatError{Field: "MonthNames", Err: errMonthName}
+		}
+	}
+
+	err = crf.validateColumns()
+	if err != nil {
+		return FormatError{Field: "Columns", Err: err}
+	}
+
+	if crf.Header != "" {
+		_, err = crf.headerFields()
+		if err != nil {
+			return FormatError{Field: "Header", Err: err}
+		}
+	}
+
+	for _, mr := range crf.MemoReplacements {
+		_, err = regexp.Compile(mr.Pattern)
+		if err != nil {
+			return FormatError{Field: "MemoReplacements",
+				Err: fmt.Errorf("Validate: memo replacement pattern in CSV record format: %w", err)}
+		}
+	}
+
+	err = crf.validateSeparators()
+	if err != nil {
+		return err
+	}
+
+	if crf.CommentChar != "" && !isCommentChar(crf.CommentChar) {
+		return FormatError{Field: "CommentChar", Err: errCommentChar}
+	}
+
+	if maxMinorUnitDigits < crf.MinorUnitDigits {
+		return FormatError{Field: "MinorUnitDigits", Err: errMinorUnitDigits}
 	}
 
 	return nil
 }
 
+// IsCommentChar reports whether the string is a single character other than a comma, quote or line break.
+func isCommentChar(s string) bool {
+	rs := []rune(s)
+
+	return len(rs) == 1 && !strings.ContainsRune(",\"\r\n\uFFFD", rs[0])
+}
+
 const (
 	// The inclusive limits for the number of fields in a CSV record.
 	minNFields = 3 // date, memo and amount
 	maxNFields = 20
+
+	// The maximum number of decimal places implied by minor units, which is limited by roundAmount.
+	maxMinorUnitDigits = 9
 )
 
 var (
 	errAmountOption = errors.New("validateOptions: amount field index, " +
 		"or credit and debit indexes in CSV record format cannot both be zero")
+	errCodeOption = errors.New("validateOptions: credit and debit codes in CSV record format " +
+		"require the code field index to be zero")
+	errCommentChar = errors.New("Validate: comment character in CSV record format " +
+		"must be one character other than a comma, quote or line break")
 	errDateI      = errors.New("validateIndexes: date field index in CSV record format cannot be zero")
 	errDateLayout = errors.New("Validate: date layout in CSV record format must be Go style e.g. \"" +
 		time.DateOnly + "\"")
-	errIndexUnique  = errors.New("validateIndexes: field indexes in CSV record format cannot share a non-zero value")
-	errIndexRange   = errors.New("validateIndexes: field index in CSV record format is out of range")
-	errMemoI        = errors.New("validateIndexes: memo field index in CSV record format cannot be zero")
-	errNFieldsRange = errors.New("Validate: number of fields in CSV record format is out of range")
+	errDecimalSep = errors.New("validateSeparators: decimal separator in CSV record format " +
+		"must be one character other than a digit or sign")
+	errDetectFormat    = errors.New("DetectCSVRecordFormat: no CSV record format parses any record in the statement")
+	errHeader          = errors.New("headerFields: header in CSV record format must be one CSV record")
+	errIndexUnique     = errors.New("validateIndexes: field indexes in CSV record format cannot share a non-zero value")
+	errIndexRange      = errors.New("validateIndexes: field index in CSV record format is out of range")
+	errMemoI           = errors.New("validateIndexes: memo field index in CSV record format cannot be zero")
+	errMinorUnitDigits = errors.New("Validate: minor unit digits in CSV record format is out of range")
+	errMonthName       = errors.New("Validate: month names in CSV record format cannot be empty string")
+	errNFieldsRange    = errors.New("Validate: number of fields in CSV record format is out of range")
+	errSignOption      = errors.New("validateOptions: sign field index in CSV record format " +
+		"requires the amount field index to be non-zero")
+	errSignedOption = errors.New("validateOptions: signed debits and signed credits and debits " +
+		"in CSV record format cannot both be set")
+	errSpecKey      = errors.New("ParseCSVRecordFormatSpec: unknown key in CSV record format specification")
+	errSpecPair     = errors.New("ParseCSVRecordFormatSpec: CSV record format specification must be key=value pairs")
+	errThousandsSep = errors.New("validateSeparators: thousands separator in CSV record format " +
+		"must be empty or one character other than a digit, sign or the decimal separator")
 )
 
 /*
@@ -149,22 +659,23 @@ ValidateIndexes returns nil if the field indexes in this CSV record format are v
 Indexes must be <= nFields.
 Each non-zero index must be unique.
 Required indexes must be non-zero.
-If not, validateIndexes returns the first error.
+If not, validateIndexes returns the first error as a FormatError.
 */
 func (crf CSVRecordFormat) validateIndexes() error {
-	is := [...]uint8{crf.AmountI, crf.CodeI, crf.CreditI, crf.CurrencyI, crf.DateI, crf.DebitI,
-		crf.MemoI, crf.OtherAccountI, crf.ThisAccountI}
+	is := crf.indexFields()
 
 	var used [maxNFields + 1]bool
 
-	for _, i := range is {
+	for _, name := range slices.Sorted(maps.Keys(is)) {
+		i := *is[name]
+
 		switch {
 		case crf.NFields < i:
-			return errIndexRange
+			return FormatError{Field: name, Err: errIndexRange}
 		case i == 0:
 			// These CSV records do not contain this field.
 		case used[i]:
-			return errIndexUnique
+			return FormatError{Field: name, Err: errIndexUnique}
 		default:
 			used[i] = true
 		}
@@ -172,9 +683,9 @@ func (crf CSVRecordFormat) validateIndexes() error {
 
 	switch {
 	case crf.DateI == 0:
-		return errDateI
+		return FormatError{Field: "DateI", Err: errDateI}
 	case crf.MemoI == 0:
-		return errMemoI
+		return FormatError{Field: "MemoI", Err: errMemoI}
 	default:
 		return nil
 	}
@@ -183,15 +694,148 @@ func (crf CSVRecordFormat) validateIndexes() error {
 /*
 ValidateOptions returns nil if the combination of optional field indexes
 in this CSV record format is valid.
-If not, validateOptions returns the error.
+If not, validateOptions returns the error as a FormatError.
 */
 func (crf CSVRecordFormat) validateOptions() error {
 	switch {
+	case crf.CodeI != 0 && (crf.CreditCode != "" || crf.DebitCode != ""):
+		return FormatError{Field: "CodeI", Err: errCodeOption}
+	case crf.SignedDebit && crf.SignedCreditDebit:
+		return FormatError{Field: "SignedCreditDebit", Err: errSignedOption}
+	case crf.SignI != 0 && crf.AmountI == 0:
+		return FormatError{Field: "SignI", Err: errSignOption}
 	case crf.AmountI != 0:
 		return nil
 	case crf.CreditI != 0 && crf.DebitI != 0:
 		return nil
 	default:
-		return errAmountOption
+		return FormatError{Field: "AmountI", Err: errAmountOption}
+	}
+}
+
+/*
+ValidateSeparators returns nil if the decimal and thousands separators in this CSV record format are valid.
+If not, validateSeparators returns the first error as a FormatError.
+*/
+func (crf CSVRecordFormat) validateSeparators() error {
+	// An empty decimal separator, as in a format built in code, is '.'.
+	ds, ts := cmp.Or(crf.DecimalSeparator, "."), crf.ThousandsSeparator
+
+	switch {
+	case !isSeparator(ds):
+		return FormatError{Field: "DecimalSeparator", Err: errDecimalSep}
+	case ts == "":
+		return nil
+	case !isSeparator(ts) || ts == ds:
+		return FormatError{Field: "ThousandsSeparator", Err: errThousandsSep}
+	default:
+		return nil
+	}
+}
+
+// IsSeparator reports whether the string is a single character other than a digit or sign.
+func isSeparator(s string) bool {
+	rs := []rune(s)
+	if len(rs) != 1 {
+		return false
 	}
+
+	r := rs[0]
+
+	return !unicode.IsDigit(r) && r != '-' && r != '+'
+}
+
+/*
+ParseDate returns the date parsed from the start of text according to the first of the date layouts
+in this CSV record format that succeeds.
+Before parsing, local month names in text are substituted by their English names.
+If it fails to parse a date, parseDate returns the error for the first layout.
+*/
+func (crf CSVRecordFormat) parseDate(text string) (string, error) {
+	for _, mn := range crf.MonthNames {
+		text = strings.Replace(text, mn.Local, mn.English, 1)
+	}
+
+	d, err := ParseDate(text, crf.DateLayout)
+	if err == nil || len(crf.DateLayouts) == 0 {
+		return d, err
+	}
+
+	d, err2 := ParseDateLayouts(text, crf.DateLayouts...)
+	if err2 != nil {
+		return "", err
+	}
+
+	return d, nil
+}
+
+/*
+HeaderFields returns the fields of the header record in this CSV record format.
+If the format is fixed width, the header is split into fields by its columns.
+If the header is empty string, headerFields returns no fields.
+If it fails to parse the header as one CSV record, headerFields returns the error.
+*/
+func (crf CSVRecordFormat) headerFields() ([]string, error) {
+	switch {
+	case crf.Header == "":
+		return nil, nil
+	case crf.IsFixedWidth():
+		return crf.splitColumns(crf.Header), nil
+	}
+
+	fs, err := csv.NewReader(strings.NewReader(crf.Header)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("headerFields: header in CSV record format: %w", err)
+	} else if len(fs) != 1 {
+		return nil, errHeader
+	}
+
+	return fs[0], nil
+}
+
+/*
+DetectCSVRecordFormat returns the index of the CSV record format, among the formats, that best fits the statement.
+The first format whose header, or column names, matches the statement's first record fits best.
+Failing that, the best format is the first that parses the most records of the statement into transactions.
+Formats are assumed to be valid.
+If no format parses any record, DetectCSVRecordFormat returns an error.
+
+This heuristic usually tells apart formats that have a header or different numbers of fields,
+but formats that differ only in, say, their date layout may be mistaken for each other.
+*/
+func DetectCSVRecordFormat(statement []byte, crfs []CSVRecordFormat) (int, error) {
+	first, _ := csv.NewReader(StripBOM(bytes.NewReader(statement))).Read()
+
+	for i, crf := range crfs {
+		hfs, _ := crf.headerFields()
+		if hfs != nil && slices.Equal(hfs, first) {
+			return i, nil
+		}
+
+		if len(crf.ColumnNames) != 0 && first != nil {
+			_, err := crf.resolveColumnNames(first)
+			if err == nil {
+				return i, nil
+			}
+		}
+	}
+
+	best, bestN := -1, 0
+
+	for i, crf := range crfs {
+		/*
+			This account is set, so that records are not rejected for lacking it,
+			which does not depend on the format.
+		*/
+		ts, _ := TranslateCSV(bytes.NewReader(statement), crf, "Detected", "")
+		if bestN < len(ts) {
+			best, bestN = i, len(ts)
+		}
+	}
+
+	if best < 0 {
+		return 0, errDetectFormat
+	}
+
+	return best, nil
 }
@@ -22,10 +22,22 @@ If not, see <https://www.gnu.org/licenses/>.
 package transaction
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 )
 
+/*
+OutputDateLayout is the Go-style date layout in which transactions' dates are written
+as Ledger journal entries, this module's CSV records and GnuCash import records e.g. "01/02/2006".
+It defaults to this module's layout.
+Programs may change it after verifying the layout by calling function IsDateLayout.
+OFX documents and JSON arrays are not affected.
+*/
+var OutputDateLayout = time.DateOnly
+
 // Reports whether dl is a Go-style date layout.
 func IsDateLayout(dl string) bool {
 	d, _ := time.Parse(dl, dl)
@@ -47,9 +59,116 @@ func ParseDate(text, layout string) (string, error) {
 		return "", fmt.Errorf("ParseDate: %w", err)
 	}
 
+	if layout == time.DateOnly {
+		// The text is already in this module's layout, so avoid allocating another string.
+		return d, nil
+	}
+
 	return date.Format(time.DateOnly), nil
 }
 
+/*
+ParseDateLayouts returns the date parsed from the start of text according to the first layout that succeeds.
+It assumes the layouts are valid.
+If it fails to parse a date with every layout, ParseDateLayouts returns the error for the first layout.
+*/
+func ParseDateLayouts(text string, layouts ...string) (string, error) {
+	var firstErr error
+
+	for _, l := range layouts {
+		d, err := ParseDate(text, l)
+		if err == nil {
+			return d, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr == nil {
+		return "", errNoDateLayouts
+	}
+
+	return "", firstErr
+}
+
+var errNoDateLayouts = errors.New("ParseDateLayouts: there must be at least one date layout")
+
+/*
+DateLayoutCandidates are the Go-style date layouts tried in order by DetectDateLayout.
+Programs may add layouts to this list.
+*/
+var DateLayoutCandidates = []string{
+	"2006-01-02", "2006/01/02", "2006.01.02", "20060102",
+	"02/01/2006", "01/02/2006", "02-01-2006", "01-02-2006", "02.01.2006", "01.02.2006",
+	"02/01/06", "01/02/06", "02-01-06", "01-02-06", "02.01.06", "01.02.06",
+	"02 Jan 2006", "2 Jan 2006", "02-Jan-2006", "02-Jan-06", "Jan 02, 2006", "Jan 2, 2006",
+	"02 January 2006", "2 January 2006", "January 02, 2006", "January 2, 2006",
+}
+
+var (
+	errDetectDateLayout = errors.New("DetectDateLayout: no candidate date layout parses every sample")
+	errDateAmbiguous    = errors.New("DetectDateLayout: samples parse as both day first and month first; " +
+		"add a sample whose day is after the 12th")
+)
+
+/*
+DetectDateLayout returns the first of DateLayoutCandidates that parses every sample date e.g. from a statement,
+for starting a new CSV record format.
+A sample may be followed by a time e.g. "02/01/2006 15:04", which is ignored as it is by ParseDate.
+Day and month are told apart by samples whose day is after the 12th, so "13/01/2006" fits "02/01/2006",
+but if the samples fit layouts that give them different dates, DetectDateLayout returns an error.
+If no layout parses every sample, DetectDateLayout returns an error too.
+*/
+func DetectDateLayout(samples []string) (string, error) {
+	var fits []string
+
+	for _, l := range DateLayoutCandidates {
+		if fitsDateLayout(samples, l) {
+			fits = append(fits, l)
+		}
+	}
+
+	if len(fits) == 0 {
+		return "", errDetectDateLayout
+	}
+
+	for _, s := range samples {
+		d, _ := ParseDate(strings.TrimSpace(s), fits[0])
+
+		for _, l := range fits[1:] {
+			other, _ := ParseDate(strings.TrimSpace(s), l)
+			if other != d {
+				return "", errDateAmbiguous
+			}
+		}
+	}
+
+	return fits[0], nil
+}
+
+/*
+FitsDateLayout reports whether the layout parses each sample date, which is at least one,
+without leaving digits after the date.
+*/
+func fitsDateLayout(samples []string, layout string) bool {
+	if len(samples) == 0 {
+		return false
+	}
+
+	for _, s := range samples {
+		s = strings.TrimSpace(s)
+
+		_, err := ParseDate(s, layout)
+		if n := len(trimDate(s, layout)); err != nil || (n < len(s) && unicode.IsDigit(rune(s[n]))) {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 ParseModuleDate returns the date in this module's default layout from the start of text.
 The layout is YYYY-MM-DD also known as [time.DateOnly] and [ISO 8601 extended date].
@@ -61,7 +180,11 @@ func ParseModuleDate(text string) (string, error) {
 	return ParseDate(text, time.DateOnly)
 }
 
-// TrimDate returns the start of the text trimmed to the length of layout or the text whichever is shorter.
+/*
+TrimDate returns the start of the text trimmed to the length of layout or the text whichever is shorter.
+If the layout has elements of variable width e.g. "January" or "2", trimDate instead returns
+as many space separated words from the start of the text as there are in the layout.
+*/
 func trimDate(text, layout string) string {
 	var (
 		dlLen = len(layout)
@@ -71,9 +194,66 @@ func trimDate(text, layout string) string {
 	switch {
 	case dlLen == 0 || tLen == 0:
 		return ""
+	case !isFixedWidthLayout(layout):
+		return trimWords(text, len(strings.Fields(layout)))
 	case dlLen < tLen:
 		return text[0:dlLen]
 	default:
 		return text
 	}
 }
+
+/*
+IsFixedWidthLayout reports whether every date formatted in the layout is as long as the layout.
+Dates with a long month and day, and a short month and day, show elements of variable width.
+*/
+func isFixedWidthLayout(layout string) bool {
+	var buf [64]byte
+
+	for _, d := range []time.Time{
+		time.Date(2006, time.December, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2006, time.May, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if len(d.AppendFormat(buf[:0], layout)) != len(layout) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TrimWords returns the start of the text up to the end of its nth space separated word.
+func trimWords(text string, n int) string {
+	end := 0
+
+	for range n {
+		start := end + len(text[end:]) - len(strings.TrimLeft(text[end:], " "))
+		if start == len(text) {
+			break
+		}
+
+		end = start + len(text[start:])
+		if i := strings.IndexByte(text[start:], ' '); i >= 0 {
+			end = start + i
+		}
+	}
+
+	return text[:end]
+}
+
+/*
+StringDate returns the date, which is in this module's layout, in the layout.
+If the date cannot be parsed, stringDate returns it unchanged.
+*/
+func stringDate(date, layout string) string {
+	if layout == time.DateOnly {
+		return date
+	}
+
+	d, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return date
+	}
+
+	return d.Format(layout)
+}
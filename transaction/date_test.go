@@ -0,0 +1,99 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import "testing"
+
+func TestParseDateLocalMonthNames(t *testing.T) {
+	crf := CSVRecordFormat{
+		DateLayout: "2 January 2006",
+		MonthNames: []MonthName{
+			{Local: "février", English: "February"},
+			{Local: "mai", English: "May"},
+			{Local: "septembre", English: "September"},
+		},
+	}
+
+	tests := []struct {
+		text, want string
+	}{
+		{"5 février 2025", "2025-02-05"},
+		{"12 mai 2025", "2025-05-12"},
+		{"30 septembre 2025", "2025-09-30"},
+		{"30 septembre 2025 10:15", "2025-09-30"},
+	}
+
+	for _, tt := range tests {
+		got, err := crf.parseDate(tt.text)
+		if err != nil || got != tt.want {
+			t.Errorf("parseDate(%q) = %q, %v; want %q", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestTrimDate(t *testing.T) {
+	tests := []struct {
+		text, layout, want string
+	}{
+		{"2025-09-30 10:15", "2006-01-02", "2025-09-30"},
+		{"2025-09", "2006-01-02", "2025-09"},
+		{"", "2006-01-02", ""},
+		{"September 12, 2025 10:15", "January 2, 2006", "September 12, 2025"},
+		{"  5 May 2025", "2 Jan 2006", "  5 May 2025"},
+		{"5 May", "2 Jan 2006", "5 May"},
+	}
+
+	for _, tt := range tests {
+		if got := trimDate(tt.text, tt.layout); got != tt.want {
+			t.Errorf("trimDate(%q, %q) = %q; want %q", tt.text, tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestDetectDateLayout(t *testing.T) {
+	tests := []struct {
+		samples []string
+		want    string
+	}{
+		{[]string{"2025-09-30", "2025-10-01"}, "2006-01-02"},
+		{[]string{"13/01/2025 10:15", "02/02/2025"}, "02/01/2006"},
+		{[]string{"01/13/2025"}, "01/02/2006"},
+		{[]string{"05 Jan 2025", "12 Jan 2025"}, "02 Jan 2006"},
+		{[]string{"5 Jan 2025", "12 Jan 2025"}, "2 Jan 2006"},
+		{[]string{"Jan 5, 2025"}, "Jan 2, 2006"},
+		{[]string{"5 September 2025"}, "2 January 2006"},
+		{[]string{"September 12, 2025"}, "January 02, 2006"},
+		{[]string{"September 12, 2025", "May 5, 2025"}, "January 2, 2006"},
+	}
+
+	for _, tt := range tests {
+		if got, err := DetectDateLayout(tt.samples); err != nil || got != tt.want {
+			t.Errorf("DetectDateLayout(%q) = %q, %v; want %q", tt.samples, got, err, tt.want)
+		}
+	}
+
+	for _, samples := range [][]string{nil, {"02/01/2025"}, {"not a date"}} {
+		if got, err := DetectDateLayout(samples); err == nil {
+			t.Errorf("DetectDateLayout(%q) = %q; want error", samples, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+/*
+A Column is the range of character positions of a field in a fixed-width record.
+Positions start at one and the range includes both its start and end e.g. 1 to 10 for "1982-10-03".
+*/
+type Column struct {
+	Start, End int
+}
+
+// IsFixedWidth reports whether this CSV record format is for fixed-width records rather than CSV records.
+func (crf CSVRecordFormat) IsFixedWidth() bool {
+	return len(crf.Columns) != 0
+}
+
+/*
+ParseFixed parses this transaction from the fixed-width record according to the format.
+The record is split into fields at the format's columns, then parsed as ParseCSV parses fields.
+It assumes the format is valid and fixed width.
+If ParseFixed fails to parse the transaction, it returns the first error.
+*/
+func (t *Transaction) ParseFixed(record string, crf CSVRecordFormat) error {
+	return t.ParseCSV(crf.splitColumns(record), crf)
+}
+
+/*
+SplitColumns returns the fields at this CSV record format's columns in the fixed-width record,
+trimmed of leading and trailing white space.
+A field beyond the end of the record is empty string.
+*/
+func (crf CSVRecordFormat) splitColumns(record string) []string {
+	rs := []rune(record)
+	fs := make([]string, len(crf.Columns))
+
+	for i, c := range crf.Columns {
+		start, end := min(c.Start-1, len(rs)), min(c.End, len(rs))
+		fs[i] = strings.TrimSpace(string(rs[start:end]))
+	}
+
+	return fs
+}
+
+/*
+NewFixedWidthRecordReader returns a reader of fixed-width records from r, without its leading UTF-8 BOM.
+Each line is a record, except blank lines and comment lines which are skipped.
+*/
+func newFixedWidthRecordReader(r io.Reader, crf CSVRecordFormat) recordReader {
+	s := bufio.NewScanner(StripBOM(r))
+	n := 0
+
+	return func() ([]string, int, error) {
+		for s.Scan() {
+			n++
+
+			ln := strings.TrimSuffix(s.Text(), "\r")
+			if strings.TrimSpace(ln) != "" && (crf.CommentChar == "" || !strings.HasPrefix(ln, crf.CommentChar)) {
+				return crf.splitColumns(ln), n, nil
+			}
+		}
+
+		err := s.Err()
+		if err != nil {
+			return nil, n, err
+		}
+
+		return nil, n, io.EOF
+	}
+}
+
+var errColumns = errors.New("validateColumns: columns in CSV record format must be one per field, " +
+	"each starting at a position from one and ending at or after it")
+
+/*
+ValidateColumns returns nil if this CSV record format has no columns,
+or has one valid column for each field.
+If not, validateColumns returns the error.
+*/
+func (crf CSVRecordFormat) validateColumns() error {
+	if !crf.IsFixedWidth() {
+		return nil
+	}
+
+	if len(crf.Columns) != int(crf.NFields) {
+		return errColumns
+	}
+
+	for _, c := range crf.Columns {
+		if c.Start < 1 || c.End < c.Start {
+			return errColumns
+		}
+	}
+
+	return nil
+}
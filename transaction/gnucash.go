@@ -0,0 +1,66 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"encoding/csv"
+	"math"
+	"strings"
+)
+
+const (
+	GnuCash = "gnucash" // The name of the [GnuCash] transaction CSV import format.
+
+	/*
+		The header record of the GnuCash transaction CSV import format,
+		whose columns match those offered by GnuCash's import assistant.
+
+		[GnuCash]: https://www.gnucash.org
+	*/
+	StartGnuCash = "Date,Num,Description,Notes,Account,Deposit,Withdrawal,Transfer Account\n"
+)
+
+/*
+StringGnuCash returns this transaction as a record in the GnuCash transaction CSV import format.
+Its date is in OutputDateLayout.
+A positive amount is a deposit and a negative amount is a withdrawal, which is written without its sign.
+The record belongs after StartGnuCash.
+*/
+func (t Transaction) StringGnuCash() string {
+	var dep, wdl string
+
+	if t.Amount < 0 {
+		wdl = stringUnsignedAmount(math.Abs(t.Amount))
+	} else {
+		dep = stringUnsignedAmount(t.Amount)
+	}
+
+	fs := []string{stringDate(t.Date, OutputDateLayout), t.Code, t.Memo, t.Note, t.ThisAccount, dep, wdl, t.OtherAccount}
+
+	var sb strings.Builder
+
+	cw := csv.NewWriter(&sb)
+	_ = cw.Write(fs) // Writing to a strings.Builder cannot fail.
+	cw.Flush()
+
+	return sb.String()
+}
@@ -22,17 +22,35 @@ If not, see <https://www.gnu.org/licenses/>.
 package transaction
 
 import (
+	"bufio"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	Ledger = "lent" // The name of the Ledger journal entry format.
 
+	/*
+		The name of the [hledger] journal entry format.
+		Entries written by StringHledger are valid hledger entries,
+		including amounts with currency symbols or codes and metadata comments.
+
+		[hledger]: https://hledger.org
+	*/
+	Hledger = "hledger"
+
+	// The name of the Ledger price directive format e.g. "P 2025-05-05 EUR 0.85 GBP".
+	Prices = "prices"
+
 	/*
 		The start and end lines for Ledger block comments
 		(see the "Commenting Your Journal" section of the [Ledger 3 manual].
@@ -45,6 +63,10 @@ const (
 	// The start and end Ledger global comment lines around a mirror entry.
 	StartMirrorEntry = "# mirror entry\n"
 	EndMirrorEntry   = "# end mirror entry\n"
+
+	// The Ledger states of a transaction (see "State Flags" in the Ledger 3 manual).
+	Cleared = "*"
+	Pending = "!"
 )
 
 /*
@@ -67,6 +89,42 @@ func IsLedgerCurrency(s string) bool {
 	return true
 }
 
+/*
+CurrencySymbols are the known currency symbols e.g. "$" for dollars.
+A Ledger amount is written with a known symbol as its prefix e.g. "$5.00"
+and with any other currency as its suffix e.g. "5.00 GBP".
+Programs may add symbols to this list.
+*/
+var CurrencySymbols = []string{"$", "£", "€", "¥", "₹", "₩", "₪", "₫", "₱", "₽", "₺", "₴", "₦", "฿"}
+
+// IsCurrencySymbol reports whether the string is one of CurrencySymbols.
+func IsCurrencySymbol(s string) bool {
+	return slices.Contains(CurrencySymbols, s)
+}
+
+/*
+IsStrictCurrency reports whether the string is empty, a known currency symbol
+or a three-letter upper-case currency code such as "GBP".
+*/
+func IsStrictCurrency(s string) bool {
+	return s == "" || IsCurrencySymbol(s) || isCurrencyCode(s)
+}
+
+// IsCurrencyCode reports whether the string is a three-letter upper-case currency code such as "GBP".
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+
+	for _, r := range s {
+		if r < 'A' || 'Z' < r {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 IsLedgerIndented reports whether the line starts with a white space character
 used by Ledger to indent postings and comments belonging to an entry.
@@ -86,6 +144,269 @@ func IsLedgerIndented(line string) bool {
 	}
 }
 
+// A LedgerEntry represents a dated Ledger journal entry.
+type LedgerEntry struct {
+	Date string // The entry's date in layout YYYY-MM-DD.
+	Time string // The entry's optional time of day in layout hh:mm:ss, which orders entries on the same date.
+	Text string // The entry's lines including its postings and comments.
+
+	// Whether the entry was marked as a mirror, which only ParseAllLedgerEntries returns.
+	Mirror bool
+}
+
+/*
+Account returns the account of the first posting in this Ledger journal entry.
+If the entry has no postings, Account returns the empty string.
+*/
+func (e LedgerEntry) Account() string {
+	lns := strings.Split(e.Text, "\n")
+
+	for _, ln := range lns[1:] {
+		p := strings.TrimLeft(ln, " \t")
+
+		switch {
+		case p == "" || strings.HasPrefix(p, ";"):
+			// This line is blank or an entry comment.
+		default:
+			a, _, _ := strings.Cut(strings.ReplaceAll(p, "\t", "  "), "  ")
+
+			return a
+		}
+	}
+
+	return ""
+}
+
+/*
+ParseLedgerJournal reads a stream of Ledger journals and returns the transactions of their dated entries
+in the order they were read e.g. for a program reporting on a journal.
+The entries are extracted as by ParseLedgerEntries, so those marked as mirrors are discarded,
+then each is parsed by ParseLedger.
+It assumes the date layout is valid.
+If it fails to read the stream, ParseLedgerJournal returns the error.
+If it fails to parse an entry, ParseLedgerJournal skips the entry then continues,
+and returns the transactions parsed with all the errors joined.
+*/
+func ParseLedgerJournal(r io.Reader, dateLayout string) ([]Transaction, error) {
+	es, err := ParseLedgerEntries(r, dateLayout)
+	if err != nil {
+		return nil, fmt.Errorf("ParseLedgerJournal: %w", err)
+	}
+
+	return parseLedgerJournal(es, dateLayout)
+}
+
+/*
+ParseAllLedgerJournal is as ParseLedgerJournal except that it also returns the transactions of entries
+marked as mirrors, as ParseAllLedgerEntries does, e.g. for a program checking whether transactions
+are already in a journal.
+*/
+func ParseAllLedgerJournal(r io.Reader, dateLayout string) ([]Transaction, error) {
+	es, err := ParseAllLedgerEntries(r, dateLayout)
+	if err != nil {
+		return nil, fmt.Errorf("ParseAllLedgerJournal: %w", err)
+	}
+
+	return parseLedgerJournal(es, dateLayout)
+}
+
+/*
+ParseLedgerJournal returns the transactions parsed from the Ledger journal entries by ParseLedger,
+skipping those it fails to parse, with all the errors joined.
+*/
+func parseLedgerJournal(es []LedgerEntry, dateLayout string) ([]Transaction, error) {
+	var (
+		errs []error
+		ts   []Transaction
+	)
+
+	for _, e := range es {
+		var t Transaction
+
+		err := t.ParseLedger(e.Text, dateLayout)
+		if err != nil {
+			ln, _, _ := strings.Cut(e.Text, "\n")
+			errs = append(errs, fmt.Errorf("entry %q: %w", ln, err))
+
+			continue
+		}
+
+		ts = append(ts, t)
+	}
+
+	return ts, errors.Join(errs...)
+}
+
+/*
+ParseLedgerEntries reads a stream of Ledger journals and returns entries with dates.
+Other content is discarded, including dated entries marked as mirrors and Ledger block comments.
+If it fails to read the stream or parse the date of an entry, ParseLedgerEntries returns the error.
+
+For further information on dated entries (or transactions) and block comments,
+see "Transactions and Comments" and "Commenting on your journal" in the [Ledger 3 manual].
+*/
+func ParseLedgerEntries(r io.Reader, dateLayout string) ([]LedgerEntry, error) {
+	es, err := parseLedgerEntries(r, dateLayout, false)
+	if err != nil {
+		return es, fmt.Errorf("ParseLedgerEntries: %w", err)
+	}
+
+	return es, nil
+}
+
+/*
+ParseAllLedgerEntries is as ParseLedgerEntries except that it also returns dated entries marked as mirrors,
+with their Mirror set, e.g. for a program keeping them for an audit trail.
+*/
+func ParseAllLedgerEntries(r io.Reader, dateLayout string) ([]LedgerEntry, error) {
+	es, err := parseLedgerEntries(r, dateLayout, true)
+	if err != nil {
+		return es, fmt.Errorf("ParseAllLedgerEntries: %w", err)
+	}
+
+	return es, nil
+}
+
+/*
+ParseLedgerEntries returns the dated entries read from the stream of Ledger journals,
+including those marked as mirrors only if keepMirrors is set.
+*/
+func parseLedgerEntries(r io.Reader, dateLayout string, keepMirrors bool) ([]LedgerEntry, error) {
+	var (
+		es                            []LedgerEntry
+		e                             LedgerEntry
+		inBlockComment, inMirrorEntry bool
+		lnN                           int
+	)
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		ln := s.Text() + "\n"
+		lnN++
+
+		if inBlock(&inBlockComment, ln, StartBlockComment, EndBlockComment) {
+			continue
+		}
+
+		// If mirrors are kept, the start and end lines are discarded below as neither dated nor indented.
+		if inBlock(&inMirrorEntry, ln, StartMirrorEntry, EndMirrorEntry) && !keepMirrors {
+			continue
+		}
+
+		switch {
+		case unicode.IsDigit(rune(ln[0])):
+			if e.Date != "" {
+				es = append(es, e)
+			}
+
+			d, err := ParseDate(ln, dateLayout)
+			if err != nil {
+				return es, fmt.Errorf("line %v: %w", lnN, err)
+			}
+
+			// This line starts with a date and is the first line in the next entry.
+			e.Date, e.Text, e.Mirror = d, ln, inMirrorEntry
+			e.Time, _ = cutLedgerTime(skipLedgerAuxDate(strings.TrimSuffix(ln[len(trimDate(ln, dateLayout)):], "\n")))
+		case IsLedgerIndented(ln):
+			// This line is indented and belongs to the current entry.
+			e.Text += ln
+		}
+	}
+
+	err := s.Err()
+	if err != nil {
+		return es, err
+	}
+
+	if e.Date != "" {
+		es = append(es, e)
+	}
+
+	return es, nil
+}
+
+/*
+NormaliseLedgerDates copies a stream of Ledger journals from r to w,
+rewriting the date and any auxiliary date of each dated entry in this module's layout YYYY-MM-DD.
+Each date is parsed according to the first of the layouts that succeeds,
+so journals from different eras may mix layouts e.g. "2006/01/02" and "01-02-2006".
+As a date ends at the first white space, the layouts cannot contain spaces.
+Everything else, including the rest of each entry's first line and Ledger block comments, is copied unchanged.
+It assumes the layouts are valid.
+If it fails to read or write the stream, or to parse a date, NormaliseLedgerDates returns the error.
+*/
+func NormaliseLedgerDates(r io.Reader, w io.Writer, layouts ...string) error {
+	var (
+		inBlockComment bool
+		lnN            int
+	)
+
+	s := bufio.NewScanner(r)
+
+	for s.Scan() {
+		ln := s.Text() + "\n"
+		lnN++
+
+		if !inBlock(&inBlockComment, ln, StartBlockComment, EndBlockComment) && unicode.IsDigit(rune(ln[0])) {
+			d, rest, err := cutLedgerDate(ln, layouts)
+			if err != nil {
+				return fmt.Errorf("NormaliseLedgerDates: line %v: %w", lnN, err)
+			}
+
+			if aux, found := strings.CutPrefix(rest, "="); found {
+				var ad string
+
+				ad, rest, err = cutLedgerDate(aux, layouts)
+				if err != nil {
+					return fmt.Errorf("NormaliseLedgerDates: line %v: auxiliary %w", lnN, err)
+				}
+
+				d += "=" + ad
+			}
+
+			ln = d + rest
+		}
+
+		_, err := io.WriteString(w, ln)
+		if err != nil {
+			return fmt.Errorf("NormaliseLedgerDates: %w", err)
+		}
+	}
+
+	err := s.Err()
+	if err != nil {
+		return fmt.Errorf("NormaliseLedgerDates: %w", err)
+	}
+
+	return nil
+}
+
+var errLedgerDate = errors.New("date must match one of the layouts")
+
+/*
+CutLedgerDate returns the date at the start of the text in this module's layout and the rest of the text,
+according to the first of the layouts that parses the whole of the date.
+The date runs up to the first white space or "=", which starts an auxiliary date,
+so layouts of variable width such as "1/2/2006" are parsed correctly e.g. "12/25/2024".
+If no layout parses it, cutLedgerDate returns an error.
+*/
+func cutLedgerDate(text string, layouts []string) (string, string, error) {
+	i := strings.IndexAny(text, " \t\n=")
+	if i < 0 {
+		i = len(text)
+	}
+
+	for _, l := range layouts {
+		d, err := time.Parse(l, text[:i])
+		if err == nil {
+			return d.Format(time.DateOnly), text[i:], nil
+		}
+	}
+
+	return "", text, errLedgerDate
+}
+
 /*
 LoadLedgerAccountNames returns a list of Ledger account names loaded from the named XML file.
 If it fails to load the list, LedgerAccounts returns the first error.
@@ -116,21 +437,363 @@ func LoadLedgerAccountNames(fileName string) ([]string, error) {
 	return as.Accounts, nil
 }
 
-// StringLedger returns this transaction as a Ledger journal entry.
-func (t Transaction) StringLedger() string {
-	a := stringAmount(t.Amount)
+/*
+ParseLedger parses this transaction from the Ledger journal entry.
+The entry's first line has the date, optional time, status and code, then the memo e.g. "2025-05-05 * (MT) Transfer".
+Alternatively, the code can be in a metadata comment line in the entry e.g. "; code: MT".
+Other metadata comment lines, whose keys are valid tag keys e.g. "; imported: 2026-10-15", are parsed as tags,
+as are comment lines of tags without values e.g. "; :reconciled:",
+while the first other comment line e.g. "; Paid to: Bob" or metadata with key "note" is the note.
+If there is metadata with key "memo", it is the memo and the first line has the payee instead.
+
+The first posting gives this account, the amount and currency,
+while the last posting gives the other account.
+If the first posting has no amount, the amount is the negated amount of the last posting.
+If there are three postings, the middle one gives the fee account and fee.
+It assumes the date layout is valid.
+If it fails to parse the entry, ParseLedger returns the first error.
+*/
+func (t *Transaction) ParseLedger(entry, dateLayout string) error {
+	lns := strings.Split(strings.TrimSuffix(entry, "\n"), "\n")
 
-	cu := t.Currency
-	switch len(cu) {
-	case 0:
-		// There is no currency for the amount.
-	case 1:
-		a = cu + a // This amount has a currency symbol.
+	err := t.parseLedgerFirstLine(lns[0], dateLayout)
+	if err != nil {
+		return err
+	}
+
+	var (
+		memo string   // The memo from metadata, if any, in which case the first line has the payee.
+		ps   []string // The postings.
+	)
+
+	for _, ln := range lns[1:] {
+		tln := strings.TrimLeft(ln, " \t")
+
+		c, found := strings.CutPrefix(tln, ";")
+		if !found {
+			if tln != "" {
+				ps = append(ps, tln)
+			}
+
+			continue
+		}
+
+		if ks, ok := parseLedgerTags(c); ok {
+			for _, k := range ks {
+				t.SetTag(k, "")
+			}
+
+			continue
+		}
+
+		k, v, found := strings.Cut(c, ":")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+
+		switch {
+		case !found || !IsTagKey(k):
+			// This line is a comment, not metadata, so the first one is the note.
+			if t.Note == "" {
+				t.Note = strings.TrimSpace(c)
+			}
+		case k == ledgerCodeKey:
+			if t.Code == "" {
+				t.Code = v
+			}
+		case k == ledgerMemoKey:
+			if memo == "" {
+				memo = v
+			}
+		case k == ledgerNoteKey:
+			if t.Note == "" {
+				t.Note = v
+			}
+		default:
+			t.SetTag(k, v)
+		}
+	}
+
+	if memo != "" {
+		t.Payee, t.Memo = t.Memo, memo
+	}
+
+	return t.parseLedgerPostings(ps)
+}
+
+/*
+ParseLedgerTags returns the keys of the tags in a Ledger comment of tags without values e.g. ":reconciled:urgent:".
+If the comment is not only tags, parseLedgerTags returns false.
+*/
+func parseLedgerTags(comment string) ([]string, bool) {
+	c := strings.TrimSpace(comment)
+	if len(c) < len("::") || c[0] != ':' || c[len(c)-1] != ':' {
+		return nil, false
+	}
+
+	ks := strings.Split(c[1:len(c)-1], ":")
+	for _, k := range ks {
+		if !IsTagKey(k) {
+			return nil, false
+		}
+	}
+
+	return ks, true
+}
+
+var (
+	errLedgerAmount   = errors.New("parseLedgerAmount: amount must be a decimal with optional currency")
+	errLedgerPostings = errors.New("parseLedgerPostings: entry must have two or three postings " +
+		"and an amount in the first or last")
+	errLedgerThousands = errors.New("stripLedgerThousands: commas in an amount must separate thousands " +
+		"before a decimal point e.g. \"1,234.56\"")
+)
+
+/*
+ParseLedgerPostings parses this transaction's accounts, amount, currency and fee from the postings of a Ledger entry.
+If it fails to parse the postings, parseLedgerPostings returns the first error.
+*/
+func (t *Transaction) parseLedgerPostings(postings []string) error {
+	n := len(postings)
+	if n < 2 || 3 < n {
+		return errLedgerPostings
+	}
+
+	var (
+		as, cus []string
+		ns      []float64
+	)
+
+	for _, p := range postings {
+		// Discard any comment after the posting.
+		p, _, _ = strings.Cut(strings.ReplaceAll(p, "\t", "  "), "  ;")
+
+		a, amt, _ := strings.Cut(p, "  ")
+
+		n, cu, err := parseLedgerAmount(amt)
+		if err != nil {
+			return err
+		}
+
+		as, ns, cus = append(as, a), append(ns, n), append(cus, cu)
+	}
+
+	t.ThisAccount, t.OtherAccount = as[0], as[n-1]
+
+	switch {
+	case ns[0] != 0:
+		t.Amount, t.Currency = ns[0], cus[0]
+	case ns[n-1] != 0:
+		t.Amount, t.Currency = -ns[n-1], cus[n-1]
 	default:
-		a = a + " " + cu // This amount has a currency code.
+		return errLedgerPostings
+	}
+
+	if n == 3 {
+		t.FeeAccount, t.Fee = as[1], ns[1]
+	}
+
+	return nil
+}
+
+/*
+ParseLedgerAmount returns the number and currency parsed from the Ledger amount e.g. "-1.23 GBP" or "$1,234.56".
+The amount can be followed by a balance assertion, which is discarded.
+Commas in the amount are thousands separators, which are stripped only if "." is its decimal mark.
+If the amount is empty string, parseLedgerAmount returns zero.
+If it fails to parse the amount, parseLedgerAmount returns the error.
+*/
+func parseLedgerAmount(amount string) (float64, string, error) {
+	amount, _, _ = strings.Cut(amount, "=")
+	amount = strings.TrimSpace(amount)
+
+	if amount == "" {
+		return 0, "", nil
+	}
+
+	var sign string
+
+	if r, found := strings.CutPrefix(amount, "-"); found && !isNumberStart(r) {
+		// The minus sign precedes a currency symbol e.g. "-$1.23".
+		sign, amount = "-", r
+	}
+
+	var num, cu string
+
+	if isNumberStart(amount) {
+		num, cu, _ = strings.Cut(amount, " ")
+	} else {
+		i := strings.IndexFunc(amount, func(r rune) bool {
+			return unicode.IsDigit(r) || r == '-' || r == '+' || r == '.'
+		})
+		if i < 0 {
+			return 0, "", errLedgerAmount
+		}
+
+		cu, num = amount[:i], amount[i:]
+	}
+
+	cu = strings.Trim(cu, " \"")
+
+	num, err := stripLedgerThousands(strings.TrimSpace(num))
+	if err != nil {
+		return 0, "", err
+	}
+
+	n, err := parseDecimal(sign + num)
+	if err != nil {
+		return 0, "", errLedgerAmount
+	}
+
+	return n, cu, nil
+}
+
+/*
+StripLedgerThousands returns the number without the commas separating its thousands e.g. "1234.56" from "1,234.56".
+As a comma is also a decimal mark e.g. "1,50" in some locales,
+commas are only stripped from a number whose decimal mark is "." and whose digit groups after the first are three long.
+Otherwise, stripLedgerThousands returns an error.
+*/
+func stripLedgerThousands(num string) (string, error) {
+	if !strings.Contains(num, ",") {
+		return num, nil
+	}
+
+	whole, frac, found := strings.Cut(num, ".")
+	if !found || strings.Contains(frac, ",") {
+		return "", errLedgerThousands
+	}
+
+	gs := strings.Split(whole, ",")
+	for _, g := range gs[1:] {
+		if len(g) != 3 {
+			return "", errLedgerThousands
+		}
+	}
+
+	return strings.Join(gs, "") + "." + frac, nil
+}
+
+// IsNumberStart reports whether the string starts like a number i.e. with a digit, sign or decimal point.
+func isNumberStart(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	switch r := rune(s[0]); {
+	case unicode.IsDigit(r), r == '-', r == '+', r == '.':
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	ledgerCodeKey = "code" // The key for a transaction code in Ledger metadata.
+	ledgerMemoKey = "memo" // The key for the memo in Ledger metadata of an entry whose first line has the payee.
+	ledgerNoteKey = "note" // The key for a note in Ledger metadata, which would otherwise be read as metadata.
+)
+
+var errLedgerMemo = errors.New("parseLedgerFirstLine: memo cannot be empty string")
+
+/*
+ParseLedgerFirstLine parses this transaction's date, status, code and memo from the first line of a Ledger entry.
+If it fails to parse the line, parseLedgerFirstLine returns the first error.
+*/
+func (t *Transaction) parseLedgerFirstLine(line, dateLayout string) error {
+	d := trimDate(line, dateLayout)
+
+	var err error
+
+	t.Date, err = ParseDate(d, dateLayout)
+	if err != nil {
+		return err
+	}
+
+	rest := skipLedgerAuxDate(line[len(d):])
+	t.Time, rest = cutLedgerTime(rest)
+	rest = strings.TrimLeft(rest, " \t")
+
+	for _, st := range []string{Cleared, Pending} {
+		if r, found := strings.CutPrefix(rest, st); found {
+			t.Status, rest = st, strings.TrimLeft(r, " \t")
+		}
+	}
+
+	if strings.HasPrefix(rest, startCode) {
+		if c, r, found := strings.Cut(rest[len(startCode):], endCode); found {
+			t.Code, rest = c, strings.TrimLeft(r, " \t")
+		}
+	}
+
+	// A note can follow the memo after a hard separator of two spaces or a tab.
+	rest, _, _ = strings.Cut(strings.ReplaceAll(rest, "\t", "  "), "  ;")
+
+	t.Memo = strings.TrimSpace(rest)
+	if t.Memo == "" {
+		return errLedgerMemo
+	}
+
+	return nil
+}
+
+// SkipLedgerAuxDate returns the rest of a Ledger entry's first line after its date without any auxiliary date.
+func skipLedgerAuxDate(rest string) string {
+	if strings.HasPrefix(rest, "=") {
+		_, rest, _ = strings.Cut(rest, " ")
+	}
+
+	return rest
+}
+
+/*
+CutLedgerTime returns the optional time of day at the start of the rest of a Ledger entry's first line,
+after its dates e.g. "09:30" in "2025-05-05 09:30 * Transfer", in layout hh:mm:ss,
+and the rest of the line after the time.
+If there is no time, cutLedgerTime returns the empty string and the rest unchanged.
+*/
+func cutLedgerTime(rest string) (string, string) {
+	tr := strings.TrimLeft(rest, " \t")
+
+	i := strings.IndexAny(tr, " \t")
+	if i < 0 {
+		i = len(tr)
+	}
+
+	for _, l := range []string{time.TimeOnly, "15:04"} {
+		tm, err := time.Parse(l, tr[:i])
+		if err == nil {
+			return tm.Format(time.TimeOnly), tr[i:]
+		}
+	}
+
+	return "", rest
+}
+
+/*
+StringLedger returns this transaction as a Ledger journal entry, with its date in OutputDateLayout
+followed by its time, if any.
+The entry's payee, which follows the date, is the transaction's payee if it has one or else its memo.
+If the transaction has a payee, its memo follows the entry's first line as metadata with key "memo"
+e.g. "; memo: CARD PURCHASE 1234", so that ParseLedger restores it.
+The entry's note, if any, follows as a Ledger comment,
+or as metadata with key "note" if the comment would otherwise be read as metadata e.g. "; note: Ref: 1234".
+Then its tags follow as Ledger metadata comments e.g. "; source: NB.csv", ordered by key.
+A tag without a value is written as a Ledger tag e.g. "; :reconciled:".
+Both apply to the whole entry, rather than to one of its postings, so they precede the postings.
+If the transaction has a fee, the entry has a posting to the fee account
+between those to this and the other account.
+If the transaction has a balance, the posting to this account asserts it e.g. "Assets:Current  -5 GBP = 42.42 GBP".
+*/
+func (t Transaction) StringLedger() string {
+	a := stringLedgerPosting(t.ThisAccount, t.stringLedgerAmount(t.Amount))
+	if t.Balance != nil {
+		a += " = " + t.stringLedgerAmount(*t.Balance)
 	}
 
-	var co string
+	var st, co string
+
+	if t.Status != "" {
+		st = " " + t.Status
+	}
 
 	if t.Code != "" {
 		co = " "
@@ -145,16 +808,168 @@ func (t Transaction) StringLedger() string {
 		}
 	}
 
-	return fmt.Sprintf("%v%v %v\n %v  %v\n %v\n",
-		t.Date, co, t.Memo,
-		t.ThisAccount, a,
+	payee, tags := t.Memo, ""
+	if t.Payee != "" {
+		payee, tags = t.Payee, fmt.Sprintf(" ; %v: %v\n", ledgerMemoKey, t.Memo)
+	}
+
+	if t.Note != "" {
+		tags += fmt.Sprintf(" ; %v\n", stringLedgerNote(t.Note))
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(t.Tags)) {
+		if t.Tags[k] == "" {
+			tags += fmt.Sprintf(" ; :%v:\n", k)
+		} else {
+			tags += fmt.Sprintf(" ; %v: %v\n", k, t.Tags[k])
+		}
+	}
+
+	var fee string
+
+	if t.FeeAccount != "" {
+		fee = stringLedgerPosting(t.FeeAccount, t.stringLedgerAmount(t.Fee)) + "\n"
+	}
+
+	d := stringDate(t.Date, OutputDateLayout)
+	if t.Time != "" {
+		d += " " + t.Time
+	}
+
+	return fmt.Sprintf("%v%v%v %v\n%v%v\n%v %v\n",
+		d, st, co, payee,
+		tags,
+		a,
+		fee,
 		t.OtherAccount)
 }
 
+/*
+StringHledger returns this transaction as an hledger journal entry,
+which is its Ledger journal entry without the time, if any, as hledger does not accept one after the date.
+*/
+func (t Transaction) StringHledger() string {
+	t.Time = ""
+
+	return t.StringLedger()
+}
+
+/*
+StringLedgerNote returns the note as a Ledger comment, which is the note itself
+unless ParseLedger would read it as metadata e.g. "Ref: 1234" or ":reconciled:",
+in which case it is metadata with key "note" e.g. "note: Ref: 1234".
+*/
+func stringLedgerNote(note string) string {
+	k, _, found := strings.Cut(note, ":")
+	if _, ok := parseLedgerTags(note); ok || (found && IsTagKey(strings.TrimSpace(k))) {
+		return ledgerNoteKey + ": " + note
+	}
+
+	return note
+}
+
+/*
+LedgerAmountColumn is the column at which amounts in Ledger journal entries end e.g. 48,
+so that they are right-aligned across entries as by "ledger print".
+An account too long for the column is followed by two spaces, as it is when the column is 0, the default.
+*/
+var LedgerAmountColumn = 0
+
+/*
+StringLedgerPosting returns the Ledger posting line, without its line break, of the amount to the account,
+with the amount ending at LedgerAmountColumn if it can.
+*/
+func stringLedgerPosting(account, amount string) string {
+	p := " " + account
+	pad := max(2, LedgerAmountColumn-utf8.RuneCountInString(p)-utf8.RuneCountInString(amount))
+
+	return p + strings.Repeat(" ", pad) + amount
+}
+
+/*
+StringPrice returns this transaction's price as a Ledger price directive e.g. "P 2025-05-05 EUR 0.85 GBP",
+which gives the price of one unit of its currency on its date.
+If the transaction has no price or currency, StringPrice returns the empty string.
+
+See "Commodity price histories" in the [Ledger 3 manual].
+*/
+func (t Transaction) StringPrice() string {
+	if t.Price == "" || t.Currency == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("P %v %v %v\n", stringDate(t.Date, OutputDateLayout), t.Currency, t.Price)
+}
+
+/*
+StringLedgerAccounts returns Ledger account directives e.g. "account Assets:Current"
+for the distinct accounts in the transactions, ordered by name.
+They declare the accounts before entries that use them, as required by Ledger's strict checking.
+*/
+func StringLedgerAccounts(ts []Transaction) string {
+	as := make(map[string]bool)
+
+	for _, t := range ts {
+		as[t.ThisAccount], as[t.OtherAccount] = true, true
+
+		if t.FeeAccount != "" {
+			as[t.FeeAccount] = true
+		}
+	}
+
+	var sb strings.Builder
+
+	for _, a := range slices.Sorted(maps.Keys(as)) {
+		fmt.Fprintf(&sb, "account %v\n", a)
+	}
+
+	return sb.String()
+}
+
+// StringLedgerAmount returns the number as a Ledger amount in this transaction's currency.
+func (t Transaction) stringLedgerAmount(n float64) string {
+	a := stringAmount(n)
+
+	cu := t.Currency
+	switch {
+	case cu == "":
+		// There is no currency for the amount.
+	case IsCurrencySymbol(cu):
+		a = cu + a // This amount has a currency symbol.
+	default:
+		a = a + " " + cu // This amount has a currency code.
+	}
+
+	return a
+}
+
+/*
+InBlock reports whether the line from a Ledger journal is in a block delimited by start and end lines.
+It also updates the in block state.
+*/
+func inBlock(state *bool, line, startLine, endLine string) bool {
+	switch {
+	case line == startLine:
+		*state = true
+	case line == endLine:
+		*state = false
+	case *state:
+		// The line is in-between start and end lines.
+	default:
+		return false
+	}
+
+	return true
+}
+
 const (
 	// The transaction code delimiters.
 	startCode = "("
 	endCode   = ")"
 )
 
-var errCurrency = errors.New("currency must be Ledger style")
+var (
+	errCurrency       = errors.New("currency must be Ledger style")
+	errStrictCurrency = errors.New("currency must be a known symbol e.g. \"$\" or a three-letter upper-case code " +
+		"e.g. \"GBP\"")
+)
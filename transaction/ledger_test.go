@@ -0,0 +1,209 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStringHledgerWithoutTime(t *testing.T) {
+	b := 42.42
+	tr := Transaction{
+		Date: "2025-05-05", Time: "09:30:00", Status: "*", Code: "POS", Payee: "Bakery", Memo: "CARD 1234",
+		Amount: -2.1, Balance: &b, Currency: "GBP", Fee: 0.1, FeeAccount: "Expenses:Fees",
+		ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+		Tags: map[string]string{"imported": "2026-10-15"},
+	}
+
+	tests := []struct {
+		name, want string
+	}{
+		{Hledger, "2025-05-05 * (POS) Bakery\n" +
+			" ; memo: CARD 1234\n" +
+			" ; imported: 2026-10-15\n" +
+			" Assets:Current  -2.1 GBP = 42.42 GBP\n" +
+			" Expenses:Fees  0.1 GBP\n" +
+			" Expenses:Food\n"},
+		{Ledger, "2025-05-05 09:30:00 * (POS) Bakery\n" +
+			" ; memo: CARD 1234\n" +
+			" ; imported: 2026-10-15\n" +
+			" Assets:Current  -2.1 GBP = 42.42 GBP\n" +
+			" Expenses:Fees  0.1 GBP\n" +
+			" Expenses:Food\n"},
+	}
+
+	for _, tt := range tests {
+		if got := tr.StringFormat(tt.name); got != tt.want {
+			t.Errorf("StringFormat(%q) =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormaliseLedgerDates(t *testing.T) {
+	const journal = "12/25/2024=1/3/2025 * Gift\n" +
+		"    Expenses:Gifts  25 GBP\n" +
+		"    Assets:Current\n" +
+		"\n" +
+		"2025/05/05 09:30 Grocer\n" +
+		"    Expenses:Food  16.92 GBP\n" +
+		"    Assets:Current\n" +
+		"comment\n" +
+		"1/2/2025 not normalised\n" +
+		"end comment\n" +
+		"1/2/2025\tCafe\n" +
+		"    Expenses:Food  3.20 GBP\n" +
+		"    Assets:Current\n"
+
+	const want = "2024-12-25=2025-01-03 * Gift\n" +
+		"    Expenses:Gifts  25 GBP\n" +
+		"    Assets:Current\n" +
+		"\n" +
+		"2025-05-05 09:30 Grocer\n" +
+		"    Expenses:Food  16.92 GBP\n" +
+		"    Assets:Current\n" +
+		"comment\n" +
+		"1/2/2025 not normalised\n" +
+		"end comment\n" +
+		"2025-01-02\tCafe\n" +
+		"    Expenses:Food  3.20 GBP\n" +
+		"    Assets:Current\n"
+
+	var sb strings.Builder
+
+	err := NormaliseLedgerDates(strings.NewReader(journal), &sb, "2006/01/02", "1/2/2006")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sb.String(); got != want {
+		t.Errorf("NormaliseLedgerDates() =\n%s\nwant\n%s", got, want)
+	}
+
+	err = NormaliseLedgerDates(strings.NewReader("25.12.2024 Gift\n"), &sb, "2006/01/02", "1/2/2006")
+	if err == nil {
+		t.Error("NormaliseLedgerDates() of a date in no layout = nil, want error")
+	}
+}
+
+func TestParseLedgerAmountThousands(t *testing.T) {
+	tests := []struct {
+		amount  string
+		want    float64
+		wantCu  string
+		wantErr bool
+	}{
+		{"1,234.56 GBP", 1234.56, "GBP", false},
+		{"-1,234,567.89 GBP", -1234567.89, "GBP", false},
+		{"$1,234.56", 1234.56, "$", false},
+		{"-$1,234.56", -1234.56, "$", false},
+		{"1234.56 GBP = 2,000.00 GBP", 1234.56, "GBP", false},
+		{"1,50 EUR", 0, "", true},
+		{"1,234 GBP", 0, "", true},
+		{"12,34.56 GBP", 0, "", true},
+		{"1.234,56 EUR", 0, "", true},
+	}
+
+	for _, tt := range tests {
+		got, cu, err := parseLedgerAmount(tt.amount)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLedgerAmount(%q) error = %v, want error %v", tt.amount, err, tt.wantErr)
+		} else if got != tt.want || cu != tt.wantCu {
+			t.Errorf("parseLedgerAmount(%q) = %v, %q; want %v, %q", tt.amount, got, cu, tt.want, tt.wantCu)
+		}
+	}
+}
+
+func TestParseLedgerComments(t *testing.T) {
+	tests := []struct {
+		comments string
+		wantNote string
+		wantTags map[string]string
+	}{
+		{" ; Paid to: Bob\n", "Paid to: Bob", nil},
+		{" ; Bought in bulk\n ; imported: 2026-10-15\n", "Bought in bulk", map[string]string{"imported": "2026-10-15"}},
+		{" ; Ref: 1234\n", "", map[string]string{"Ref": "1234"}},
+		{" ; note: Ref: 1234\n", "Ref: 1234", nil},
+		{" ; :reconciled:urgent:\n", "", map[string]string{"reconciled": "", "urgent": ""}},
+		{" ; : no key\n", ": no key", nil},
+	}
+
+	for _, tt := range tests {
+		entry := "2025-05-05 Grocer\n" + tt.comments + " Assets:Current  -16.92 GBP\n Expenses:Food\n"
+
+		var tr Transaction
+
+		err := tr.ParseLedger(entry, time.DateOnly)
+		if err != nil {
+			t.Errorf("ParseLedger(%q) error = %v", entry, err)
+		} else if tr.Note != tt.wantNote || !maps.Equal(tr.Tags, tt.wantTags) {
+			t.Errorf("ParseLedger(%q) note %q, tags %v; want %q, %v", entry, tr.Note, tr.Tags, tt.wantNote, tt.wantTags)
+		}
+	}
+}
+
+func TestStringLedgerNoteRoundTrip(t *testing.T) {
+	for _, note := range []string{"Bought in bulk", "Paid to: Bob", "Ref: 1234", ":reconciled:", "code: 42"} {
+		want := Transaction{
+			Date: "2025-05-05", Memo: "Grocer", Note: note, Amount: -16.92, Currency: "GBP",
+			ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+		}
+
+		var got Transaction
+
+		err := got.ParseLedger(want.StringLedger(), time.DateOnly)
+		if err != nil || got.Note != note || got.Code != "" || len(got.Tags) != 0 {
+			t.Errorf("ParseLedger(%q) = note %q, code %q, tags %v, %v; want note %q",
+				want.StringLedger(), got.Note, got.Code, got.Tags, err, note)
+		}
+	}
+}
+
+func TestStringLedgerParseLedgerRoundTrip(t *testing.T) {
+	tests := []Transaction{
+		{
+			Date: "2025-05-05", Payee: "Grocer", Memo: "CARD PURCHASE 1234", Note: "Bought in bulk",
+			Amount: -16.92, Currency: "GBP", ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+			Tags: map[string]string{"imported": "2026-10-15", "reconciled": ""},
+		},
+		{
+			Date: "2025-05-06", Payee: "Bob", Memo: "Ref: 1234", Note: "Paid to: Bob",
+			Amount: 20, Currency: "GBP", ThisAccount: "Assets:Current", OtherAccount: "Income:Gifts",
+		},
+		{
+			Date: "2025-05-07", Memo: "Cafe", Note: "memo: not the memo",
+			Amount: -3.2, Currency: "GBP", ThisAccount: "Assets:Current", OtherAccount: "Expenses:Food",
+		},
+	}
+
+	for _, want := range tests {
+		var got Transaction
+
+		err := got.ParseLedger(want.StringLedger(), time.DateOnly)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseLedger(%q) = %+v, %v; want %+v", want.StringLedger(), got, err, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	OFX = "ofx" // The name of the [Open Financial Exchange] (OFX) format.
+
+	/*
+		The start and end of a minimal OFX document containing a bank statement's transactions.
+
+		[Open Financial Exchange]: https://en.wikipedia.org/wiki/Open_Financial_Exchange
+	*/
+	StartOFX = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<BANKTRANLIST>
+`
+	EndOFX = `</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+)
+
+/*
+StringOFX returns this transaction as an OFX statement transaction element.
+The element has the transaction's type (credit or debit), date, amount and memo as its name.
+It belongs in an OFX document between StartOFX and EndOFX.
+*/
+func (t Transaction) StringOFX() string {
+	tt := "CREDIT"
+	if t.Amount < 0 {
+		tt = "DEBIT"
+	}
+
+	var name strings.Builder
+
+	_ = xml.EscapeText(&name, []byte(t.Memo))
+
+	return fmt.Sprintf("<STMTTRN>\n<TRNTYPE>%v</TRNTYPE>\n<DTPOSTED>%v</DTPOSTED>\n"+
+		"<TRNAMT>%v</TRNAMT>\n<NAME>%v</NAME>\n</STMTTRN>\n",
+		tt, strings.ReplaceAll(t.Date, "-", ""), stringAmount(t.Amount), name.String())
+}
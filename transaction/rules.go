@@ -0,0 +1,370 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+)
+
+/*
+Rules adjust transactions after they have been parsed.
+A currency rule sets the currency of a transaction belonging to its this account that has none.
+An opening rule posts a transaction with its code, such as an opening balance, to an equity account.
+An other account rule replaces the default other account of a transaction belonging to its this account.
+A fee rule splits a fee from the amount of a transaction whose memo matches its pattern.
+A tag rule tags a transaction whose memo matches its pattern.
+An invert rule negates the amount of a transaction belonging to its this account.
+*/
+type Rules struct {
+	Inverts       []InvertRule       `xml:"Invert"`
+	Currencies    []CurrencyRule     `xml:"Currency"`
+	Openings      []OpeningRule      `xml:"Opening"`
+	OtherAccounts []OtherAccountRule `xml:"OtherAccount"`
+	Fees          []FeeRule          `xml:"Fee"`
+	Tags          []TagRule          `xml:"Tag"`
+}
+
+/*
+An InvertRule negates the amount and any balance of transactions belonging to this account.
+This corrects statements whose sign convention is the opposite of Ledger's,
+such as a credit card statement whose purchases are positive.
+*/
+type InvertRule struct {
+	ThisAccount string // The Ledger name of this account e.g. "Liabilities:CreditCard".
+}
+
+/*
+A CurrencyRule sets the currency of transactions belonging to this account that have no currency.
+This gives each account its default currency when statements from accounts in several currencies are translated
+together.
+*/
+type CurrencyRule struct {
+	ThisAccount string // The Ledger name of this account e.g. "Assets:Euro".
+	Currency    string // The Ledger currency e.g. "EUR".
+}
+
+/*
+An OpeningRule sets the other account of transactions with its code e.g. "OPEN",
+whose other account is DefaultOtherAccount, to its account.
+This posts opening balances to equity, rather than leaving them unbalanced in Imbalance.
+*/
+type OpeningRule struct {
+	Code    string // The transaction code marking opening balances e.g. "OPEN".
+	Account string // The Ledger name of the other account, which defaults to OpeningBalancesAccount.
+}
+
+// The default account of opening rules.
+const OpeningBalancesAccount = "Equity:Opening Balances"
+
+/*
+An OtherAccountRule sets the other account of transactions belonging to this account,
+whose other account is DefaultOtherAccount.
+This routes unclassified transactions by the account they came from.
+*/
+type OtherAccountRule struct {
+	ThisAccount string // The Ledger name of this account e.g. "Assets:Current".
+	Account     string // The Ledger name of the other account e.g. "Expenses:Unknown:Current".
+}
+
+/*
+A FeeRule splits a fee from the amount of a transaction whose memo matches the rule's pattern.
+The fee is either a fraction of the amount or a fixed amount, but not both.
+*/
+type FeeRule struct {
+	Memo     string  // The regular expression matching the memo e.g. "^CARD PURCHASE".
+	Account  string  // The Ledger name of the fee account e.g. "Expenses:Fees".
+	Fraction float64 // The fee as a fraction of the amount e.g. 0.015 for 1.5%.
+	Amount   float64 // The fee as a fixed positive amount.
+
+	memo *regexp.Regexp
+}
+
+/*
+A TagRule tags a transaction whose memo matches the rule's pattern.
+If the pattern is empty string, every transaction is tagged.
+*/
+type TagRule struct {
+	Memo  string // The regular expression matching the memo e.g. "^INTEREST".
+	Key   string // The tag's key e.g. "reconciled".
+	Value string // The tag's optional value.
+
+	memo *regexp.Regexp
+}
+
+/*
+LoadRules returns valid rules loaded from the named XML file.
+If it fails to load or validate the rules, LoadRules returns the first error.
+
+For example, file rules.xml might contain a rule for the other account of unclassified transactions
+and a rule for a foreign-transaction fee:
+
+	<Rules>
+	  <Invert>
+	    <ThisAccount>Liabilities:CreditCard</ThisAccount>
+	  </Invert>
+	  <Currency>
+	    <ThisAccount>Assets:Euro</ThisAccount>
+	    <Currency>EUR</Currency>
+	  </Currency>
+	  <Opening>
+	    <Code>OPEN</Code>
+	  </Opening>
+	  <OtherAccount>
+	    <ThisAccount>Assets:Current</ThisAccount>
+	    <Account>Expenses:Unknown:Current</Account>
+	  </OtherAccount>
+	  <Fee>
+	    <Memo> EUR$</Memo>
+	    <Account>Expenses:Fees</Account>
+	    <Fraction>0.015</Fraction>
+	  </Fee>
+	  <Tag>
+	    <Key>imported</Key>
+	    <Value>2026-10-15</Value>
+	  </Tag>
+	</Rules>
+*/
+func LoadRules(fileName string) (Rules, error) {
+	var rs Rules
+
+	bs, err := os.ReadFile(fileName)
+	if err != nil {
+		return rs, fmt.Errorf("LoadRules: %w", err)
+	}
+
+	err = xml.Unmarshal(bs, &rs)
+	if err != nil {
+		return rs, fmt.Errorf("LoadRules: %w", err)
+	}
+
+	for _, ir := range rs.Inverts {
+		if ir.ThisAccount == "" || ir.ThisAccount == DefaultOtherAccount {
+			return rs, errInvertAccount
+		}
+	}
+
+	for _, cr := range rs.Currencies {
+		switch {
+		case cr.ThisAccount == "" || cr.ThisAccount == DefaultOtherAccount:
+			return rs, errCurrencyAccount
+		case cr.Currency == "" || !IsLedgerCurrency(cr.Currency):
+			return rs, errCurrencyRule
+		}
+	}
+
+	for i, opr := range rs.Openings {
+		switch {
+		case opr.Code == "":
+			return rs, errOpeningCode
+		case opr.Account == DefaultOtherAccount:
+			return rs, errOpeningAccount
+		case opr.Account == "":
+			rs.Openings[i].Account = OpeningBalancesAccount
+		}
+	}
+
+	for _, oar := range rs.OtherAccounts {
+		err = oar.validate()
+		if err != nil {
+			return rs, err
+		}
+	}
+
+	for i := range rs.Fees {
+		err = rs.Fees[i].compile()
+		if err != nil {
+			return rs, err
+		}
+	}
+
+	for i := range rs.Tags {
+		err = rs.Tags[i].compile()
+		if err != nil {
+			return rs, err
+		}
+	}
+
+	return rs, nil
+}
+
+/*
+Apply adjusts the transaction according to these rules.
+If there is an invert rule for this account, the amount and any balance are negated.
+If the transaction has no currency, the first currency rule for this account sets it.
+If the other account is DefaultOtherAccount, the first opening rule for the code replaces it or, failing that,
+the first other account rule for this account.
+The first fee rule whose pattern matches the memo splits a fee from the amount.
+The fee is a positive amount posted to its account, like an expense,
+and the other account balances the transaction.
+A fee that is a fraction of the amount is rounded to the nearest hundredth.
+Every tag rule whose pattern matches the memo sets its tag.
+Rules built in code, rather than loaded by LoadRules, have their patterns compiled when applied.
+It assumes the rules are valid.
+*/
+func (rs Rules) Apply(t *Transaction) {
+	for _, ir := range rs.Inverts {
+		if t.ThisAccount == ir.ThisAccount {
+			t.Amount *= -1
+
+			if t.Balance != nil {
+				b := -*t.Balance
+				t.Balance = &b
+			}
+
+			break
+		}
+	}
+
+	for _, cr := range rs.Currencies {
+		if t.Currency == "" && t.ThisAccount == cr.ThisAccount {
+			t.Currency = cr.Currency
+
+			break
+		}
+	}
+
+	for _, tr := range rs.Tags {
+		if matchMemo(tr.memo, tr.Memo, t.Memo) {
+			t.SetTag(tr.Key, tr.Value)
+		}
+	}
+
+	for _, opr := range rs.Openings {
+		if t.OtherAccount == DefaultOtherAccount && t.Code == opr.Code {
+			t.OtherAccount = opr.Account
+
+			break
+		}
+	}
+
+	for _, oar := range rs.OtherAccounts {
+		if t.OtherAccount == DefaultOtherAccount && t.ThisAccount == oar.ThisAccount {
+			t.OtherAccount = oar.Account
+
+			break
+		}
+	}
+
+	for _, fr := range rs.Fees {
+		if !matchMemo(fr.memo, fr.Memo, t.Memo) {
+			continue
+		}
+
+		fee := fr.Amount
+		if fr.Fraction != 0 {
+			fee = math.Round(math.Abs(t.Amount)*fr.Fraction*100) / 100
+		}
+
+		t.Fee, t.FeeAccount = fee, fr.Account
+
+		return
+	}
+}
+
+/*
+MatchMemo reports whether the memo matches the rule's compiled pattern re or,
+if the rule was built in code and so its pattern not compiled, the pattern.
+*/
+func matchMemo(re *regexp.Regexp, pattern, memo string) bool {
+	if re == nil {
+		re = regexp.MustCompile(pattern)
+	}
+
+	return re.MatchString(memo)
+}
+
+var (
+	errCurrencyAccount = errors.New("LoadRules: currency rule this account cannot be empty string or \"" +
+		DefaultOtherAccount + "\"")
+	errCurrencyRule  = errors.New("LoadRules: currency rule currency must be a Ledger currency")
+	errFeeAccount    = errors.New("compile: fee rule account cannot be empty string or \"" + DefaultOtherAccount + "\"")
+	errFeeOption     = errors.New("compile: fee rule must have either a positive fraction or a positive amount")
+	errInvertAccount = errors.New("LoadRules: invert rule this account cannot be empty string or \"" +
+		DefaultOtherAccount + "\"")
+	errOpeningAccount = errors.New("LoadRules: opening rule account cannot be \"" + DefaultOtherAccount + "\"")
+	errOpeningCode    = errors.New("LoadRules: opening rule code cannot be empty string")
+	errOtherAccount   = errors.New("validate: other account rule accounts cannot be empty string or \"" +
+		DefaultOtherAccount + "\"")
+	errTagKey = errors.New("compile: tag rule key cannot be empty string or contain white space or a colon")
+)
+
+/*
+Validate returns nil if this other account rule is valid.
+If not, validate returns the error.
+*/
+func (oar OtherAccountRule) validate() error {
+	for _, a := range []string{oar.ThisAccount, oar.Account} {
+		if a == "" || a == DefaultOtherAccount {
+			return errOtherAccount
+		}
+	}
+
+	return nil
+}
+
+/*
+Compile validates this fee rule then compiles its memo pattern.
+If it fails, compile returns the first error.
+*/
+func (fr *FeeRule) compile() error {
+	switch {
+	case fr.Account == "" || fr.Account == DefaultOtherAccount:
+		return errFeeAccount
+	case fr.Fraction < 0 || fr.Amount < 0:
+		return errFeeOption
+	case (fr.Fraction == 0) == (fr.Amount == 0):
+		return errFeeOption
+	}
+
+	re, err := regexp.Compile(fr.Memo)
+	if err != nil {
+		return fmt.Errorf("compile: %w", err)
+	}
+
+	fr.memo = re
+
+	return nil
+}
+
+/*
+Compile validates this tag rule then compiles its memo pattern.
+If it fails, compile returns the first error.
+*/
+func (tr *TagRule) compile() error {
+	if !IsTagKey(tr.Key) {
+		return errTagKey
+	}
+
+	re, err := regexp.Compile(tr.Memo)
+	if err != nil {
+		return fmt.Errorf("compile: %w", err)
+	}
+
+	tr.memo = re
+
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Rules built in code, rather than loaded, have patterns that are not yet compiled.
+func TestApplyRulesBuiltInCode(t *testing.T) {
+	rs := Rules{
+		Fees: []FeeRule{{Memo: " EUR$", Account: "Expenses:Fees", Fraction: 0.015}},
+		Tags: []TagRule{{Memo: "^CAFE", Key: "coffee"}, {Key: "imported", Value: "2026-10-15"}},
+	}
+
+	tr := Transaction{Memo: "CAFE 3.20 EUR", Amount: -100, ThisAccount: "Assets:Current", OtherAccount: "Imbalance"}
+
+	rs.Apply(&tr)
+
+	if tr.Fee != 1.5 || tr.FeeAccount != "Expenses:Fees" {
+		t.Errorf("fee = %v to %q, want 1.5 to %q", tr.Fee, tr.FeeAccount, "Expenses:Fees")
+	}
+
+	if v, ok := tr.Tags["coffee"]; !ok || v != "" {
+		t.Errorf("tag coffee = %q, %v; want empty value", v, ok)
+	}
+
+	if v := tr.Tags["imported"]; v != "2026-10-15" {
+		t.Errorf("tag imported = %q, want %q", v, "2026-10-15")
+	}
+
+	other := Transaction{Memo: "GROCER", Amount: -10, ThisAccount: "Assets:Current", OtherAccount: "Imbalance"}
+
+	rs.Apply(&other)
+
+	if other.Fee != 0 || len(other.Tags) != 1 {
+		t.Errorf("rules applied to %q: fee %v, tags %v; want no fee and only tag imported", other.Memo, other.Fee, other.Tags)
+	}
+}
+
+func TestOpeningRuleDefaultAccount(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "rules.xml")
+
+	err := os.WriteFile(fn, []byte("<Rules><Opening><Code>OPEN</Code></Opening></Rules>"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := LoadRules(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := Transaction{
+		Date: "2025-01-01", Code: "OPEN", Memo: "Opening balance", Amount: 37.79, Currency: "GBP",
+		ThisAccount: "Assets:Current", OtherAccount: DefaultOtherAccount,
+	}
+
+	rs.Apply(&tr)
+
+	if want := "Equity:Opening Balances"; tr.OtherAccount != want {
+		t.Errorf("other account = %q, want %q", tr.OtherAccount, want)
+	}
+
+	var got Transaction
+
+	err = got.ParseLedger(tr.StringLedger(), time.DateOnly)
+	if err != nil || got.OtherAccount != tr.OtherAccount {
+		t.Errorf("ParseLedger(%q) other account = %q, %v; want %q", tr.StringLedger(), got.OtherAccount, err,
+			tr.OtherAccount)
+	}
+}
@@ -22,17 +22,31 @@ If not, see <https://www.gnu.org/licenses/>.
 /*
 Package transaction represents financial transactions as instances of type Transaction.
 It offers:
-  - parsing a transaction from a [comma-separated values (CSV)] record;
+  - parsing a transaction from a [comma-separated values (CSV)] record or a fixed-width record;
     an instance of type CSVRecordFormat configures the parser for the record format
+  - translating a statement of CSV records into transactions
   - parsing some of a transaction's fields from a [Ledger] journal entry
-  - stringing a transaction to either a Ledger journal entry or
-    this module's CSV record
+  - stringing a transaction to a Ledger journal entry, this module's CSV record,
+    an OFX statement transaction, a GnuCash import record or a Ledger price directive
+  - writing a list of transactions in those formats or JSON with a TransactionWriter
 
 [comma-separated values (CSV)]: https://en.wikipedia.org/wiki/Comma-separated_values
 [Ledger]: https://en.wikipedia.org/wiki/Ledger_(software)
 */
 package transaction
 
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
 /*
 A Transaction represents a financial transaction:
 the transfer of an amount of currency from one account to another on a date.
@@ -42,16 +56,127 @@ Optional fields may have the value empty string, while the rest must have non-ze
 */
 type Transaction struct {
 	Amount       float64
-	Code         string // This field is optional.
-	Currency     string // This field is optional.
+	Balance      *float64 // This field is optional: this account's balance after the transaction.
+	Code         string   // This field is optional.
+	Currency     string   // This field is optional.
 	Date         string
+	Fee          float64 // This field is optional: the part of the amount posted to the fee account.
+	FeeAccount   string  // This field is optional, but required if there is a fee.
 	Memo         string
+	Note         string // This field is optional: an annotation written as a Ledger comment.
 	OtherAccount string // The default value of this field is DefaultOtherAccount.
+	Payee        string // This field is optional: whom the transaction paid or was paid by e.g. a shop.
+	Price        string // This field is optional: the price of one unit of the currency as a Ledger amount.
+	Status       string // This field is optional: Cleared, Pending or the empty string.
 	ThisAccount  string
+	Time         string // This field is optional: the time of day in layout hh:mm:ss from a Ledger entry.
+
+	Tags map[string]string // This field is optional: metadata such as the transaction's source.
 }
 
 const DefaultOtherAccount = "Imbalance" // The default value for other account.
 
+/*
+AmountTolerance is the largest difference between two amounts that are considered equal.
+It allows for rounding errors in floating-point arithmetic, while being much smaller than
+the minor unit of any currency.
+*/
+const AmountTolerance = 0.000001
+
+/*
+Equal reports whether this transaction and the other have the same field values.
+Amounts, balances and fees are compared within AmountTolerance, while other fields must be identical.
+*/
+func (t Transaction) Equal(other Transaction) bool {
+	switch {
+	case math.Abs(t.Amount-other.Amount) > AmountTolerance:
+		return false
+	case math.Abs(t.Fee-other.Fee) > AmountTolerance:
+		return false
+	case t.Code != other.Code || t.Currency != other.Currency || t.Date != other.Date:
+		return false
+	case t.FeeAccount != other.FeeAccount || t.Memo != other.Memo || t.Note != other.Note:
+		return false
+	case t.Payee != other.Payee || t.Price != other.Price || t.Status != other.Status || t.Time != other.Time:
+		return false
+	case (t.Balance == nil) != (other.Balance == nil):
+		return false
+	case t.Balance != nil && math.Abs(*t.Balance-*other.Balance) > AmountTolerance:
+		return false
+	case t.OtherAccount != other.OtherAccount || t.ThisAccount != other.ThisAccount:
+		return false
+	default:
+		return maps.Equal(t.Tags, other.Tags)
+	}
+}
+
+/*
+IsMirror reports whether this transaction and the other could be the two sides of one transfer
+between their accounts, as recorded in the journals of both accounts.
+They must have the same date and currency, equal and opposite amounts within AmountTolerance,
+and this and other accounts swapped.
+*/
+func (t Transaction) IsMirror(other Transaction) bool {
+	switch {
+	case t.Date != other.Date || t.Currency != other.Currency:
+		return false
+	case math.Abs(t.Amount+other.Amount) > AmountTolerance:
+		return false
+	default:
+		return t.ThisAccount == other.OtherAccount && t.OtherAccount == other.ThisAccount
+	}
+}
+
+/*
+IsMarkedMirror reports whether this transaction is the credit side of a transfer between two accounts with journals,
+which program mcsv2lent marks as a mirror entry: its amount is positive and its this and other accounts
+are both in the list of accounts.
+*/
+func (t Transaction) IsMarkedMirror(accounts []string) bool {
+	return 0 < t.Amount && slices.Contains(accounts, t.ThisAccount) && slices.Contains(accounts, t.OtherAccount)
+}
+
+/*
+SetTag sets the value of the tag with the key in this transaction's tags, creating them if need be.
+A tag without a value, such as Ledger tag ":reconciled:", has value empty string.
+*/
+func (t *Transaction) SetTag(key, value string) {
+	if t.Tags == nil {
+		t.Tags = make(map[string]string)
+	}
+
+	t.Tags[key] = value
+}
+
+/*
+IsTagKey reports whether the string is a valid key for a transaction's tag,
+which is not empty and contains neither white space nor a colon.
+*/
+func IsTagKey(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return r == ':' || unicode.IsSpace(r)
+	})
+}
+
+/*
+String returns this transaction compactly for developers e.g. in log messages,
+with its date, this and other accounts, amount, currency, code and memo
+e.g. "2025-05-05 Assets:Current→Assets:Savings -1.23 GBP (MT) Transfer".
+Empty optional fields are left out.
+*/
+func (t Transaction) String() string {
+	s := t.Date + " " + t.ThisAccount + "→" + t.OtherAccount + " " + stringAmount(t.Amount)
+	if t.Currency != "" {
+		s += " " + t.Currency
+	}
+
+	if t.Code != "" {
+		s += " " + startCode + t.Code + endCode
+	}
+
+	return s + " " + t.Memo
+}
+
 /*
 StringFormat returns this transaction in the named format.
 If the name is not known, StringFormat returns the empty string.
@@ -60,9 +185,105 @@ func (t Transaction) StringFormat(name string) string {
 	switch name {
 	case Ledger:
 		return t.StringLedger()
-	case ModuleCSV:
+	case Hledger:
+		return t.StringHledger()
+	case ModuleCSV, ModuleCSVSummary:
 		return t.StringModuleCSV()
+	case OFX:
+		return t.StringOFX()
+	case GnuCash:
+		return t.StringGnuCash()
+	case Prices:
+		return t.StringPrice()
 	default:
 		return ""
 	}
 }
+
+// The names of the orders of transactions in a statement.
+const (
+	OrderAuto       = "auto" // Either ascending or descending, detected from the first and last dates.
+	OrderAscending  = "asc"
+	OrderDescending = "desc"
+	OrderKeep       = "keep" // Any order, which is kept.
+)
+
+var errOrder = errors.New("OrderTransactions: order name must be \"" +
+	OrderAuto + "\", \"" + OrderAscending + "\", \"" + OrderDescending + "\" or \"" + OrderKeep + "\"")
+
+/*
+OrderTransactions puts transactions read from a statement in the named order into date order ascending.
+Transactions in descending order are reversed,
+while those in ascending order or whose order is to be kept are unchanged.
+For order OrderAuto, the transactions are reversed if the first is later than the last.
+If the order name is not known, OrderTransactions returns an error.
+*/
+func OrderTransactions(ts []Transaction, name string) error {
+	n := len(ts)
+
+	switch name {
+	case OrderAuto:
+		if 2 <= n && ts[0].Date > ts[n-1].Date {
+			slices.Reverse(ts)
+		}
+	case OrderDescending:
+		slices.Reverse(ts)
+	case OrderAscending, OrderKeep:
+		// These transactions are already in order.
+	default:
+		return errOrder
+	}
+
+	return nil
+}
+
+/*
+SortTransactions sorts the transactions by date then time ascending, where no time comes first.
+The sort is stable, so transactions with the same date and time keep their order.
+*/
+func SortTransactions(ts []Transaction) {
+	slices.SortStableFunc(ts, func(a, b Transaction) int {
+		return cmp.Or(strings.Compare(a.Date, b.Date), strings.Compare(a.Time, b.Time))
+	})
+}
+
+/*
+NewCRLFWriter returns a writer that writes to w with each line feed "\n" replaced by
+a carriage return and line feed "\r\n", as expected by some consumers on Windows.
+A line feed already preceded by a carriage return, even one at the end of the previous write, is left unchanged.
+*/
+func NewCRLFWriter(w io.Writer) io.Writer {
+	return &crlfWriter{w: w}
+}
+
+type crlfWriter struct {
+	w      io.Writer
+	lastCR bool // Whether the last byte written was a carriage return.
+}
+
+func (cw *crlfWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	bs := make([]byte, 0, len(p)+bytes.Count(p, []byte("\n")))
+	prevCR := cw.lastCR
+
+	for _, b := range p {
+		if b == '\n' && !prevCR {
+			bs = append(bs, '\r')
+		}
+
+		bs = append(bs, b)
+		prevCR = b == '\r'
+	}
+
+	_, err := cw.w.Write(bs)
+	if err != nil {
+		return 0, err
+	}
+
+	cw.lastCR = prevCR
+
+	return len(p), nil
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCRLFWriter(t *testing.T) {
+	tests := []struct {
+		writes []string
+		want   string
+	}{
+		{[]string{"a\nb\n"}, "a\r\nb\r\n"},
+		{[]string{"a\r\nb\n"}, "a\r\nb\r\n"},
+		{[]string{"\"memo\r\nmore\",1\n"}, "\"memo\r\nmore\",1\r\n"},
+		{[]string{"a\r", "\nb\n"}, "a\r\nb\r\n"},
+		{[]string{"a", "\n", "\n"}, "a\r\n\r\n"},
+		{[]string{"a\r", "", "\n"}, "a\r\n"},
+		{[]string{"\n\r\n"}, "\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		var sb strings.Builder
+
+		w := NewCRLFWriter(&sb)
+		for _, s := range tt.writes {
+			n, err := w.Write([]byte(s))
+			if err != nil || n != len(s) {
+				t.Fatalf("Write(%q) = %v, %v; want %v, nil", s, n, err, len(s))
+			}
+		}
+
+		if got := sb.String(); got != tt.want {
+			t.Errorf("writes %q = %q, want %q", tt.writes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright (C) 2026 Andrew Flint.
+
+This file is part of arnhemcr/financial.
+
+Arnhemcr/financial is free software:
+you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+Arnhemcr/financial is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with arnhemcr/financial.
+If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package transaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const JSON = "json" // The name of the format: a JSON array of transaction objects.
+
+/*
+A TransactionWriter writes transactions in a format.
+A format such as OFX, GnuCash or JSON frames its transactions with a header, footer or both,
+so WriteHeader must be called before the first transaction and WriteFooter after the last.
+*/
+type TransactionWriter interface {
+	WriteHeader() error
+	WriteTransaction(t Transaction) error
+	WriteFooter() error
+}
+
+var errFormatName = errors.New("NewTransactionWriter: format name must be \"" +
+	Ledger + "\", \"" + Hledger + "\", \"" + ModuleCSV + "\", \"" + ModuleCSVSummary + "\", \"" +
+	OFX + "\", \"" + GnuCash + "\", \"" + Prices + "\" or \"" + JSON + "\"")
+
+/*
+NewTransactionWriter returns a writer of transactions to w in the named format.
+If the name is not known, NewTransactionWriter returns an error.
+*/
+func NewTransactionWriter(w io.Writer, name string) (TransactionWriter, error) {
+	switch name {
+	case Ledger, Hledger, ModuleCSV, Prices:
+		return &stringWriter{w: w, name: name}, nil
+	case ModuleCSVSummary:
+		return &summaryWriter{stringWriter: stringWriter{w: w, name: name}}, nil
+	case OFX:
+		return &stringWriter{w: w, name: name, header: StartOFX, footer: EndOFX}, nil
+	case GnuCash:
+		return &stringWriter{w: w, name: name, header: StartGnuCash}, nil
+	case JSON:
+		return &jsonWriter{w: w}, nil
+	default:
+		return nil, errFormatName
+	}
+}
+
+/*
+WriteTransactions writes the transactions in the named format in the order given,
+framed by the format's header and footer.
+If the name is not known or it fails to write, WriteTransactions returns the error.
+*/
+func WriteTransactions(w io.Writer, ts []Transaction, name string) error {
+	tw, err := NewTransactionWriter(w, name)
+	if err != nil {
+		return fmt.Errorf("WriteTransactions: %w", err)
+	}
+
+	err = tw.WriteHeader()
+	if err != nil {
+		return fmt.Errorf("WriteTransactions: %w", err)
+	}
+
+	for _, t := range ts {
+		err = tw.WriteTransaction(t)
+		if err != nil {
+			return fmt.Errorf("WriteTransactions: %w", err)
+		}
+	}
+
+	err = tw.WriteFooter()
+	if err != nil {
+		return fmt.Errorf("WriteTransactions: %w", err)
+	}
+
+	return nil
+}
+
+// A stringWriter writes each transaction as returned by StringFormat between a fixed header and footer.
+type stringWriter struct {
+	w              io.Writer
+	name           string
+	header, footer string
+}
+
+func (sw *stringWriter) WriteHeader() error {
+	_, err := io.WriteString(sw.w, sw.header)
+
+	return err
+}
+
+func (sw *stringWriter) WriteTransaction(t Transaction) error {
+	_, err := io.WriteString(sw.w, t.StringFormat(sw.name))
+
+	return err
+}
+
+func (sw *stringWriter) WriteFooter() error {
+	_, err := io.WriteString(sw.w, sw.footer)
+
+	return err
+}
+
+// A summaryWriter writes transactions as this module's CSV records followed by their summary.
+type summaryWriter struct {
+	stringWriter
+
+	ts []Transaction
+}
+
+func (sw *summaryWriter) WriteTransaction(t Transaction) error {
+	sw.ts = append(sw.ts, t)
+
+	return sw.stringWriter.WriteTransaction(t)
+}
+
+func (sw *summaryWriter) WriteFooter() error {
+	_, err := io.WriteString(sw.w, StringModuleCSVSummary(sw.ts))
+
+	return err
+}
+
+// A jsonWriter writes transactions as the elements of a JSON array, one per line.
+type jsonWriter struct {
+	w io.Writer
+	n int // The number of transactions written.
+}
+
+func (jw *jsonWriter) WriteHeader() error {
+	_, err := io.WriteString(jw.w, "[")
+
+	return err
+}
+
+func (jw *jsonWriter) WriteTransaction(t Transaction) error {
+	bs, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if jw.n == 0 {
+		sep = "\n"
+	}
+
+	jw.n++
+
+	_, err = fmt.Fprintf(jw.w, "%v%s", sep, bs)
+
+	return err
+}
+
+func (jw *jsonWriter) WriteFooter() error {
+	_, err := io.WriteString(jw.w, "\n]\n")
+
+	return err
+}